@@ -0,0 +1,253 @@
+// Package client sends requests to the scheme, or to the simulator, over a pool of
+// connections.
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/response"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultPoolSize = 4
+	// DefaultTimeout bounds the requests sent without a context deadline. The scheme can
+	// take up to 10s to process a payment.
+	DefaultTimeout = 15 * time.Second
+)
+
+var (
+	// ErrConnectionDropped is returned when the connection is closed before the response
+	// is received. The request may or may not have been processed.
+	ErrConnectionDropped = errors.New("connection dropped without a response")
+	// ErrMalformedResponse is returned when the response is not a RESPONSE|<status>|<reason> line.
+	ErrMalformedResponse = errors.New("malformed response")
+	ErrClosed            = errors.New("client closed")
+)
+
+// Response is the parsed response of the scheme, e.g. Status "ACCEPTED" and Reason
+// "Transaction processed".
+type Response = response.Response
+
+type Options struct {
+	// PoolSize is the maximum number of connections, and so of concurrent requests.
+	// Defaults to DefaultPoolSize.
+	PoolSize int
+	// Timeout bounds the requests sent with a context without deadline. Defaults to
+	// DefaultTimeout.
+	Timeout time.Duration
+	// Dial opens the connections, e.g. with a tls.Dialer. Defaults to net.Dialer.
+	Dial func(ctx context.Context, network string, address string) (net.Conn, error)
+}
+
+// Client sends the requests over a pool of connections, one request at a time per
+// connection. Broken connections are discarded and replaced by new ones on the next
+// request.
+type Client struct {
+	address string
+	options Options
+	slots   chan struct{}
+	mu      sync.Mutex
+	idle    []*conn
+	closed  bool
+}
+
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+	peeked chan error
+}
+
+func New(address string, options Options) *Client {
+	if options.PoolSize <= 0 {
+		options.PoolSize = DefaultPoolSize
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.Dial == nil {
+		options.Dial = (&net.Dialer{}).DialContext
+	}
+	return &Client{
+		address: address,
+		options: options,
+		slots:   make(chan struct{}, options.PoolSize),
+	}
+}
+
+// SendPayment sends a PAYMENT|<amount> request.
+func (c *Client) SendPayment(ctx context.Context, amount uint64) (Response, error) {
+	return c.Send(ctx, fmt.Sprintf("PAYMENT|%d", amount))
+}
+
+// SendPaymentWithID sends a PAYMENT|<id>|<amount> request, which the scheme processes only
+// once however many times it is resubmitted.
+func (c *Client) SendPaymentWithID(ctx context.Context, id string, amount uint64) (Response, error) {
+	return c.Send(ctx, fmt.Sprintf("PAYMENT|%s|%d", id, amount))
+}
+
+// Send sends a raw request and waits for its response until the context is done.
+func (c *Client) Send(ctx context.Context, request string) (Response, error) {
+	select {
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		return Response{}, ctx.Err()
+	}
+	defer func() { <-c.slots }()
+
+	conn, err := c.get(ctx)
+	if err != nil {
+		return Response{}, err
+	}
+
+	line, err := conn.roundTrip(ctx, request, c.options.Timeout)
+	if err != nil {
+		conn.Close()
+		return Response{}, requestError(ctx, err)
+	}
+	c.put(conn)
+	return parse(line)
+}
+
+// Connect opens the connections of the pool up front, instead of on the first requests.
+func (c *Client) Connect(ctx context.Context) error {
+	var errs []error
+	for range c.options.PoolSize - c.open() {
+		netConn, err := c.options.Dial(ctx, "tcp", c.address)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c.put(&conn{Conn: netConn, reader: bufio.NewReader(netConn)})
+	}
+	return errors.Join(errs...)
+}
+
+// open returns the number of connections idle or in use.
+func (c *Client) open() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.idle) + len(c.slots)
+}
+
+// Close closes the idle connections. The connections in use are closed once their
+// requests complete.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	var errs []error
+	for _, conn := range c.idle {
+		errs = append(errs, conn.Close())
+	}
+	c.idle = nil
+	return errors.Join(errs...)
+}
+
+// get returns an idle connection still open, or dials a new one.
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	for len(c.idle) > 0 {
+		conn := c.idle[len(c.idle)-1]
+		c.idle = c.idle[:len(c.idle)-1]
+		if conn.alive() {
+			c.mu.Unlock()
+			return conn, nil
+		}
+		conn.Close()
+	}
+	c.mu.Unlock()
+
+	netConn, err := c.options.Dial(ctx, "tcp", c.address)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: netConn, reader: bufio.NewReader(netConn)}, nil
+}
+
+func (c *Client) put(conn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		conn.Close()
+		return
+	}
+	conn.watch()
+	c.idle = append(c.idle, conn)
+}
+
+// watch detects the connection being closed by the server while idle.
+func (c *conn) watch() {
+	c.peeked = make(chan error, 1)
+	go func() {
+		_, err := c.reader.Peek(1)
+		c.peeked <- err
+	}()
+}
+
+// alive stops watching the connection and reports whether it is still usable.
+func (c *conn) alive() bool {
+	if err := c.SetReadDeadline(time.Now()); err != nil {
+		return false
+	}
+	err := <-c.peeked
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		// closed, or sent data nobody asked for
+		return false
+	}
+	return c.SetReadDeadline(time.Time{}) == nil
+}
+
+func (c *conn) roundTrip(ctx context.Context, request string, timeout time.Duration) (string, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(timeout)
+	}
+	if err := c.SetDeadline(deadline); err != nil {
+		return "", err
+	}
+	// unblock the request as soon as the context is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		c.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if _, err := io.WriteString(c, request+"\n"); err != nil {
+		return "", err
+	}
+	return c.reader.ReadString('\n')
+}
+
+func requestError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	var netErr net.Error
+	if deadline, ok := ctx.Deadline(); ok && errors.As(err, &netErr) && netErr.Timeout() && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return fmt.Errorf("%w: %w", ErrConnectionDropped, err)
+	}
+	return err
+}
+
+func parse(line string) (Response, error) {
+	parts := strings.SplitN(strings.TrimSuffix(line, "\n"), "|", 3)
+	if len(parts) != 3 || parts[0] != "RESPONSE" || parts[1] == "" {
+		return Response{}, fmt.Errorf("%w: %q", ErrMalformedResponse, line)
+	}
+	return Response{Status: parts[1], Reason: parts[2]}, nil
+}
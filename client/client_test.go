@@ -0,0 +1,204 @@
+package client_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/form3tech-oss/interview-simulator/client"
+	"github.com/form3tech-oss/interview-simulator/simtest"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const rules = `
+rules:
+  - amounts: [13]
+    status: REJECTED
+    reason: Unlucky
+  - amounts: [404]
+    fault: drop
+  - amounts: [500]
+    fault: malformed
+  - amount: {min: 1000}
+    delay: 200ms
+`
+
+type ClientTestSuite struct {
+	suite.Suite
+	sim    *simtest.Simulator
+	client *client.Client
+}
+
+func TestClientSuite(t *testing.T) {
+	suite.Run(t, &ClientTestSuite{})
+}
+
+func (suite *ClientTestSuite) SetupTest() {
+	suite.sim = simtest.Start(suite.T(), simtest.WithScenario(rules))
+	suite.client = client.New(suite.sim.Addr, client.Options{PoolSize: 2})
+}
+
+func (suite *ClientTestSuite) TearDownTest() {
+	suite.client.Close()
+}
+
+func (suite *ClientTestSuite) Test_SendPayment() {
+	tests := []struct {
+		name     string
+		amount   uint64
+		expected client.Response
+	}{
+		{name: "Accepted", amount: 10, expected: client.Response{Status: "ACCEPTED", Reason: "Transaction processed"}},
+		{name: "Rejected", amount: 13, expected: client.Response{Status: "REJECTED", Reason: "Unlucky"}},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			resp, err := suite.client.SendPayment(context.Background(), tt.amount)
+
+			suite.NoError(err)
+			suite.Equal(tt.expected, resp)
+		})
+	}
+}
+
+func (suite *ClientTestSuite) Test_SendPaymentWithID() {
+	resp, err := suite.client.SendPaymentWithID(context.Background(), "p-1", 10)
+	suite.NoError(err)
+	suite.Equal("ACCEPTED", resp.Status)
+
+	resp, err = suite.client.SendPaymentWithID(context.Background(), "p-1", 20)
+	suite.NoError(err)
+	suite.Equal(client.Response{Status: "REJECTED", Reason: "Duplicate payment"}, resp)
+}
+
+func (suite *ClientTestSuite) Test_ReusesConnections() {
+	for range 3 {
+		_, err := suite.client.SendPayment(context.Background(), 10)
+		suite.NoError(err)
+	}
+
+	entries := suite.sim.WaitForJournal(3)
+	suite.Equal(entries[0].ConnectionID, entries[1].ConnectionID)
+	suite.Equal(entries[0].ConnectionID, entries[2].ConnectionID)
+}
+
+func (suite *ClientTestSuite) Test_PoolLimitsConnections() {
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := suite.client.SendPayment(context.Background(), 1000)
+			suite.NoError(err)
+		}()
+	}
+	wg.Wait()
+
+	connections := make(map[uint64]struct{})
+	for _, entry := range suite.sim.WaitForJournal(4) {
+		connections[entry.ConnectionID] = struct{}{}
+	}
+	suite.Len(connections, 2)
+}
+
+func (suite *ClientTestSuite) Test_RequestDeadline() {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := suite.client.SendPayment(ctx, 1000)
+
+	suite.ErrorIs(err, context.DeadlineExceeded)
+	suite.Less(time.Since(start), 200*time.Millisecond, "Request was not interrupted at the deadline")
+}
+
+func (suite *ClientTestSuite) Test_Timeout() {
+	c := client.New(suite.sim.Addr, client.Options{Timeout: 50 * time.Millisecond})
+	defer c.Close()
+
+	_, err := c.SendPayment(context.Background(), 1000)
+
+	var netErr net.Error
+	suite.True(errors.As(err, &netErr) && netErr.Timeout(), "Expected a timeout, got %v", err)
+}
+
+func (suite *ClientTestSuite) Test_ConnectionDroppedAndReconnected() {
+	_, err := suite.client.SendPayment(context.Background(), 404)
+	suite.ErrorIs(err, client.ErrConnectionDropped)
+
+	resp, err := suite.client.SendPayment(context.Background(), 10)
+	suite.NoError(err)
+	suite.Equal("ACCEPTED", resp.Status)
+}
+
+func (suite *ClientTestSuite) Test_MalformedResponse() {
+	_, err := suite.client.SendPayment(context.Background(), 500)
+
+	suite.ErrorIs(err, client.ErrMalformedResponse)
+}
+
+func (suite *ClientTestSuite) Test_ReconnectsWhenIdleConnectionIsClosed() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	defer listener.Close()
+	// answers one request per connection, then closes it
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			reader := bufio.NewReader(conn)
+			if _, err := reader.ReadString('\n'); err == nil {
+				conn.Write([]byte("RESPONSE|ACCEPTED|Transaction processed\n"))
+			}
+			conn.Close()
+		}
+	}()
+	c := client.New(listener.Addr().String(), client.Options{PoolSize: 1})
+	defer c.Close()
+
+	for range 2 {
+		resp, err := c.SendPayment(context.Background(), 10)
+		suite.NoError(err)
+		suite.Equal("ACCEPTED", resp.Status)
+		// wait for the connection to be closed
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func (suite *ClientTestSuite) Test_Connect() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	defer listener.Close()
+	c := client.New(listener.Addr().String(), client.Options{PoolSize: 3})
+	defer c.Close()
+
+	suite.Require().NoError(c.Connect(context.Background()))
+	suite.Require().NoError(c.Connect(context.Background()), "Connecting again should not open more connections")
+
+	listener.(*net.TCPListener).SetDeadline(time.Now().Add(100 * time.Millisecond))
+	accepted := 0
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			break
+		}
+		defer conn.Close()
+		accepted++
+	}
+	suite.Equal(3, accepted)
+}
+
+func (suite *ClientTestSuite) Test_ClosedClient() {
+	suite.client.Close()
+
+	_, err := suite.client.SendPayment(context.Background(), 10)
+
+	suite.ErrorIs(err, client.ErrClosed)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type listenerFlag struct {
+	address      string
+	scenarioFile string
+	gracePeriod  time.Duration
+}
+
+// listenerFlags collects the -listen flags, formatted as
+// <address>[,scenario=<file>][,grace=<duration>].
+type listenerFlags []listenerFlag
+
+func (f *listenerFlags) String() string {
+	addresses := make([]string, 0, len(*f))
+	for _, listener := range *f {
+		addresses = append(addresses, listener.address)
+	}
+	return strings.Join(addresses, " ")
+}
+
+func (f *listenerFlags) Set(value string) error {
+	options := strings.Split(value, ",")
+	listener := listenerFlag{address: options[0]}
+	if listener.address == "" {
+		return fmt.Errorf("missing address in %q", value)
+	}
+	for _, option := range options[1:] {
+		key, val, _ := strings.Cut(option, "=")
+		switch key {
+		case "scenario":
+			listener.scenarioFile = val
+		case "grace":
+			period, err := time.ParseDuration(val)
+			if err != nil {
+				return fmt.Errorf("invalid grace period in %q: %w", value, err)
+			}
+			listener.gracePeriod = period
+		default:
+			return fmt.Errorf("unknown option %q in %q", key, value)
+		}
+	}
+	*f = append(*f, listener)
+	return nil
+}
@@ -1,38 +1,161 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/admin"
+	"github.com/form3tech-oss/interview-simulator/internal/config"
+	"github.com/form3tech-oss/interview-simulator/internal/coordinator"
+	"github.com/form3tech-oss/interview-simulator/internal/journal"
+	"github.com/form3tech-oss/interview-simulator/internal/metrics"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	"github.com/form3tech-oss/interview-simulator/internal/session"
 	tcp_listener "github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
 	"github.com/rs/zerolog"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 )
 
-const (
-	PORT        = 8080
-	WAIT_PERIOD = 5 * time.Second
+const ADMIN_PORT = 8081
+
+var (
+	scenarioFile = flag.String("scenario", "", "YAML or JSON file with the scenario rules")
+	journalFile  = flag.String("journal", "", "file to append the handled requests to, as JSON lines")
+	tlsCert      = flag.String("tls-cert", "", "server certificate file, enables TLS")
+	tlsKey       = flag.String("tls-key", "", "server private key file")
+	tlsClientCA  = flag.String("tls-client-ca", "", "CA bundle to verify client certificates, enables mutual TLS")
+	adminAddress = flag.String("admin", fmt.Sprintf("localhost:%d", ADMIN_PORT), "address of the admin API")
+	listeners    listenerFlags
+	configFlags  = config.RegisterFlags(flag.CommandLine)
 )
 
+func init() {
+	flag.Var(&listeners, "listen", "address to accept connections on, as <address>[,scenario=<file>][,grace=<duration>]; can be repeated (default localhost:8080)")
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(replay(os.Args[2:], os.Stdout))
+	}
+
+	flag.Parse()
 	logger := zerolog.New(os.Stdout).With().Timestamp().Logger()
-	listener, err := tcp_listener.New(PORT, WAIT_PERIOD, &tcp_listener.TcpListenerDeps{
+
+	cfg, err := configFlags.Load(os.LookupEnv)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error loading configuration.")
+		os.Exit(1)
+	}
+	logger = logger.Level(cfg.Level())
+
+	rules, err := loadScenario(*scenarioFile, scenario.NewDefault(cfg.DelayThreshold, cfg.DelayCap), logger)
+	if err != nil {
+		os.Exit(1)
+	}
+	if len(listeners) == 0 {
+		listeners = listenerFlags{{address: cfg.Address()}}
+	}
+	configs := make([]coordinator.ListenerConfig, 0, len(listeners))
+	for _, listener := range listeners {
+		config := coordinator.ListenerConfig{Address: listener.address, GracePeriod: cfg.GracePeriod, Scenario: rules}
+		if listener.gracePeriod > 0 {
+			config.GracePeriod = listener.gracePeriod
+		}
+		if config.Scenario, err = loadScenario(listener.scenarioFile, rules, logger); err != nil {
+			os.Exit(1)
+		}
+		configs = append(configs, config)
+	}
+
+	registry := metrics.NewRegistry()
+	deps := &tcp_listener.TcpListenerDeps{
 		Logger:     logger,
 		Listener:   tcp_listener.NetListener{},
 		NewScanner: tcp_listener.BufioScanner{},
-	})
+		Metrics:    metrics.NewListener(registry),
+	}
+	options := tcp_listener.Options{
+		Timeouts:          tcp_listener.Timeouts{Idle: cfg.IdleTimeout, Read: cfg.ReadTimeout, Write: cfg.WriteTimeout},
+		MaxRequestSize:    cfg.MaxRequestSize,
+		OversizedRequests: tcp_listener.OversizedPolicy(cfg.OversizedRequests),
+		MaxConnections:    cfg.MaxConnections,
+		ConnectionLimit:   tcp_listener.ConnectionPolicy(cfg.ConnectionLimit),
+		Heartbeat:         cfg.Heartbeat,
+		RateLimit: tcp_listener.RateLimit{
+			PerConnection: cfg.RateLimit,
+			Global:        cfg.GlobalRateLimit,
+			Burst:         cfg.RateLimitBurst,
+			Policy:        tcp_listener.RateLimitPolicy(cfg.RateLimitPolicy),
+			Reason:        cfg.RateLimitReason,
+		},
+		Sequencing: tcp_listener.Sequencing{
+			Enabled: cfg.Sequencing,
+			Gaps:    tcp_listener.GapPolicy(cfg.SequenceGaps),
+			Reason:  cfg.SequenceGapReason,
+		},
+	}
+	if *tlsCert != "" {
+		config, err := tcp_listener.NewTLSConfig(*tlsCert, *tlsKey, *tlsClientCA)
+		if err != nil {
+			logger.Error().Err(err).Msg("Error loading TLS configuration.")
+			os.Exit(1)
+		}
+		deps.Listener = tcp_listener.TLSListener{Config: config}
+	}
+	if cfg.Participants != "" {
+		list, err := session.Load(cfg.Participants)
+		if err != nil {
+			logger.Error().Err(err).Str("file", cfg.Participants).Msg("Error loading participants.")
+			os.Exit(1)
+		}
+		options.Participants = list
+	}
+	if *journalFile != "" {
+		file, err := journal.Open(*journalFile)
+		if err != nil {
+			logger.Error().Err(err).Msg("Error opening journal.")
+			os.Exit(1)
+		}
+		defer file.Close()
+		deps.Journal = file
+	}
+
+	simulator, err := coordinator.New(configs, options, deps)
 	if err != nil {
-		logger.Error().Err(err).Msg("Error creating listener.")
+		logger.Error().Err(err).Msg("Error creating listeners.")
 		os.Exit(1)
 	}
 
-	go listener.Start()
+	adminServer, err := admin.New(*adminAddress, simulator, registry, logger)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error creating admin server.")
+		os.Exit(1)
+	}
+
+	go simulator.Start()
+	go adminServer.Start()
 
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 	<-shutdown
 
 	logger.Info().Msg("Shutting down service...")
-	listener.Stop()
+	simulator.Stop()
+	adminServer.Stop()
 	logger.Info().Msg("Service stopped.")
 }
+
+// loadScenario loads the scenario file, or returns the fallback if no file is set.
+func loadScenario(file string, fallback *scenario.Scenario, logger zerolog.Logger) (*scenario.Scenario, error) {
+	if file == "" {
+		return fallback, nil
+	}
+	rules, err := scenario.Load(file)
+	if err != nil {
+		logger.Error().Err(err).Str("file", file).Msg("Error loading scenario.")
+		return nil, err
+	}
+	logger.Info().Str("scenario", rules.Name).Msg("Loaded scenario.")
+	return rules, nil
+}
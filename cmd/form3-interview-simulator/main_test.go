@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/config"
 	"github.com/stretchr/testify/require"
 	"net"
 	"os"
@@ -15,7 +16,7 @@ func Test_TestSigIntStopsTheService(t *testing.T) {
 
 	time.Sleep(1 * time.Second)
 
-	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", PORT))
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", config.DefaultPort))
 	require.NoError(t, err, "Failed to connect to server")
 	require.NotNil(t, conn)
 	conn.Close()
@@ -28,7 +29,7 @@ func Test_TestSigIntStopsTheService(t *testing.T) {
 
 	time.Sleep(1 * time.Second)
 
-	conn, err = net.Dial("tcp", fmt.Sprintf(":%d", PORT))
+	conn, err = net.Dial("tcp", fmt.Sprintf(":%d", config.DefaultPort))
 	require.Error(t, err)
 	require.Nil(t, conn)
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/config"
+	"github.com/form3tech-oss/interview-simulator/internal/journal"
+	"github.com/form3tech-oss/interview-simulator/internal/session"
+	"io"
+	"time"
+)
+
+// replay re-sends the requests of a journal to a target and reports the responses that
+// differ from the recorded ones. It returns the exit code of the command.
+func replay(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	journalFile := flags.String("journal", "", "journal file to replay")
+	target := flags.String("target", fmt.Sprintf("localhost:%d", config.DefaultPort), "address of the simulator or scheme endpoint")
+	timeout := flags.Duration("timeout", 15*time.Second, "timeout for each request")
+	participants := flags.String("participants", "", "participants file of the simulator, to log on with their secrets")
+	sequencing := flags.Bool("sequencing", false, "number the requests, for journals recorded with sequencing")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *journalFile == "" {
+		fmt.Fprintln(out, "replay: -journal is required")
+		return 2
+	}
+
+	entries, err := journal.ReadFile(*journalFile)
+	if err != nil {
+		fmt.Fprintf(out, "replay: error reading journal: %v\n", err)
+		return 1
+	}
+
+	requests := entries
+	if *participants != "" {
+		list, err := session.Load(*participants)
+		if err != nil {
+			fmt.Fprintf(out, "replay: error loading participants: %v\n", err)
+			return 1
+		}
+		// the secrets of the logon requests are redacted in the journal
+		requests = make([]journal.Entry, len(entries))
+		for i, entry := range entries {
+			entry.Request = list.Unredact(entry.Request)
+			requests[i] = entry
+		}
+	}
+
+	results := journal.Replay(*target, requests, journal.ReplayOptions{Timeout: *timeout, Sequencing: *sequencing})
+	mismatches := 0
+	for i, result := range results {
+		if result.Matches() {
+			continue
+		}
+		mismatches++
+		fmt.Fprintf(out, "connection %d: %s\n", result.Entry.ConnectionID, entries[i].Request)
+		fmt.Fprintf(out, "  - %s\n", result.Entry.Response)
+		if result.Err != nil {
+			fmt.Fprintf(out, "  + error: %v\n", result.Err)
+		} else {
+			fmt.Fprintf(out, "  + %s\n", result.Actual)
+		}
+	}
+	fmt.Fprintf(out, "replayed %d requests, %d mismatches\n", len(results), mismatches)
+	if mismatches > 0 {
+		return 1
+	}
+	return 0
+}
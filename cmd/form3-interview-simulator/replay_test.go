@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/journal"
+	"github.com/form3tech-oss/interview-simulator/internal/session"
+	tcp_listener "github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_ReplaySessions(t *testing.T) {
+	dir := t.TempDir()
+	participantsFile := filepath.Join(dir, "participants.yaml")
+	require.NoError(t, os.WriteFile(participantsFile, []byte("participants: [{id: bank-a, secret: s3cret}]"), 0o600))
+	participants, err := session.Load(participantsFile)
+	require.NoError(t, err)
+	journalFile := filepath.Join(dir, "journal.jsonl")
+	file, err := journal.Open(journalFile)
+	require.NoError(t, err)
+
+	listener, err := tcp_listener.New("localhost:0", 0, tcp_listener.Options{Participants: participants}, &tcp_listener.TcpListenerDeps{Logger: zerolog.Nop(), Listener: tcp_listener.NetListener{}, NewScanner: tcp_listener.BufioScanner{}, Journal: file})
+	require.NoError(t, err)
+	go listener.Start()
+	t.Cleanup(listener.Stop)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err, "Failed to connect to server")
+	reader := bufio.NewReader(conn)
+	for _, request := range []string{"LOGON|bank-a|s3cret", "PAYMENT|10"} {
+		_, err = fmt.Fprintf(conn, "%s\n", request)
+		require.NoError(t, err)
+		_, err = reader.ReadString('\n')
+		require.NoError(t, err)
+	}
+	conn.Close()
+	require.Eventually(t, func() bool {
+		entries, err := journal.ReadFile(journalFile)
+		return err == nil && len(entries) == 2
+	}, time.Second, 10*time.Millisecond, "Requests were not recorded")
+
+	var out bytes.Buffer
+	code := replay([]string{"-journal", journalFile, "-target", listener.Addr().String(), "-participants", participantsFile}, &out)
+
+	require.Equal(t, 0, code, out.String())
+	require.Contains(t, out.String(), "replayed 2 requests, 0 mismatches")
+
+	out.Reset()
+	code = replay([]string{"-journal", journalFile, "-target", listener.Addr().String()}, &out)
+
+	require.Equal(t, 1, code, "Logon should fail without the participants")
+	require.Contains(t, out.String(), "LOGON|bank-a|***")
+	require.NotContains(t, out.String(), "s3cret")
+}
+
+func Test_ReplaySequencing(t *testing.T) {
+	journalFile := filepath.Join(t.TempDir(), "journal.jsonl")
+	file, err := journal.Open(journalFile)
+	require.NoError(t, err)
+
+	listener, err := tcp_listener.New("localhost:0", 0, tcp_listener.Options{Sequencing: tcp_listener.Sequencing{Enabled: true}}, &tcp_listener.TcpListenerDeps{Logger: zerolog.Nop(), Listener: tcp_listener.NetListener{}, NewScanner: tcp_listener.BufioScanner{}, Journal: file})
+	require.NoError(t, err)
+	go listener.Start()
+	t.Cleanup(listener.Stop)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err, "Failed to connect to server")
+	reader := bufio.NewReader(conn)
+	for _, request := range []string{"1|PAYMENT|10", "2|PAYMENT|20"} {
+		_, err = fmt.Fprintf(conn, "%s\n", request)
+		require.NoError(t, err)
+		_, err = reader.ReadString('\n')
+		require.NoError(t, err)
+	}
+	conn.Close()
+	require.Eventually(t, func() bool {
+		entries, err := journal.ReadFile(journalFile)
+		return err == nil && len(entries) == 2
+	}, time.Second, 10*time.Millisecond, "Requests were not recorded")
+
+	var out bytes.Buffer
+	code := replay([]string{"-journal", journalFile, "-target", listener.Addr().String(), "-sequencing"}, &out)
+
+	require.Equal(t, 0, code, out.String())
+	require.Contains(t, out.String(), "replayed 2 requests, 0 mismatches")
+
+	out.Reset()
+	code = replay([]string{"-journal", journalFile, "-target", listener.Addr().String()}, &out)
+
+	require.Equal(t, 1, code, "Requests should be out of sequence without -sequencing")
+	require.Contains(t, out.String(), "RESPONSE|REJECTED|Invalid sequence number")
+}
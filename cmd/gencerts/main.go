@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/certs"
+	"os"
+	"strings"
+)
+
+// gencerts generates a test CA with a server and a client certificate for running the
+// simulator with mutual TLS.
+func main() {
+	out := flag.String("out", "certs", "directory to write the certificates to")
+	hosts := flag.String("hosts", "localhost,127.0.0.1", "comma separated DNS names and IPs of the server certificate")
+	clientName := flag.String("client-name", "participant", "common name of the client certificate")
+	flag.Parse()
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *out, err)
+		os.Exit(1)
+	}
+	err := certs.Generate(*out, certs.Options{Hosts: strings.Split(*hosts, ","), ClientName: *clientName})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating certificates: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Certificates written to %s\n", *out)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/loadgen"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// loadgen sends payments to the simulator over many connections at a target rate, and
+// reports the throughput, latency percentiles and outcome of the requests.
+func main() {
+	target := flag.String("target", "localhost:8080", "address of the simulator or scheme endpoint")
+	connections := flag.Int("connections", 10, "number of connections")
+	rate := flag.Float64("rate", 100, "payments per second across all connections, 0 for as fast as possible")
+	duration := flag.Duration("duration", 10*time.Second, "time to send payments for, 0 to stop after -requests")
+	requests := flag.Int("requests", 0, "number of payments to send, 0 to stop after -duration")
+	amounts := flag.String("amounts", "1-100", "amounts or amount ranges with optional weights, e.g. 10=80,101-10000=15,20000=5")
+	timeout := flag.Duration("timeout", 15*time.Second, "timeout for each payment")
+	format := flag.String("format", "text", "report format, text or json")
+	flag.Parse()
+
+	distribution, err := loadgen.ParseDistribution(*amounts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing -amounts: %v\n", err)
+		os.Exit(2)
+	}
+	if *connections <= 0 {
+		fmt.Fprintln(os.Stderr, "-connections must be positive")
+		os.Exit(2)
+	}
+	if *duration <= 0 && *requests <= 0 {
+		fmt.Fprintln(os.Stderr, "One of -duration or -requests is required")
+		os.Exit(2)
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown format %q\n", *format)
+		os.Exit(2)
+	}
+
+	// stop sending payments on SIGINT and report the ones sent so far
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report := loadgen.Run(ctx, loadgen.Config{
+		Target:      *target,
+		Connections: *connections,
+		Rate:        *rate,
+		Duration:    *duration,
+		Requests:    *requests,
+		Amounts:     distribution,
+		Timeout:     *timeout,
+	})
+
+	if *format == "json" {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(1)
+	}
+}
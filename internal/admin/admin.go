@@ -0,0 +1,152 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	tcp_listener "github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"github.com/rs/zerolog"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Simulator is the part of the running simulator controlled by the admin API.
+type Simulator interface {
+	Connections() []tcp_listener.ConnectionInfo
+	Requests() []tcp_listener.RequestInfo
+	Stop()
+	GracePeriod() time.Duration
+	SetGracePeriod(time.Duration)
+	SetScenario(*scenario.Scenario)
+}
+
+// Server exposes the admin API over HTTP:
+//
+//	GET  /connections   lists the open connections.
+//	GET  /requests      lists the in-flight requests.
+//	POST /drain         stops accepting connections and drains the in-flight requests.
+//	GET  /grace-period  returns the grace period.
+//	PUT  /grace-period  changes the grace period, e.g. {"gracePeriod": "3s"}.
+//	PUT  /scenario      replaces the scenario with the YAML or JSON rules in the body.
+//	GET  /metrics       exposes the metrics in the Prometheus text format.
+type Server struct {
+	server    *http.Server
+	listener  net.Listener
+	simulator Simulator
+	metrics   http.Handler
+	logger    zerolog.Logger
+}
+
+// New creates the admin server. The metrics handler is optional.
+func New(address string, simulator Simulator, metrics http.Handler, logger zerolog.Logger) (*Server, error) {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error listening admin connection.")
+		return nil, err
+	}
+	s := &Server{listener: l, simulator: simulator, metrics: metrics, logger: logger}
+	s.server = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	return s, nil
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /connections", s.connections)
+	mux.HandleFunc("GET /requests", s.requests)
+	mux.HandleFunc("POST /drain", s.drain)
+	mux.HandleFunc("GET /grace-period", s.gracePeriod)
+	mux.HandleFunc("PUT /grace-period", s.setGracePeriod)
+	mux.HandleFunc("PUT /scenario", s.setScenario)
+	if s.metrics != nil {
+		mux.Handle("GET /metrics", s.metrics)
+	}
+	return mux
+}
+
+func (s *Server) Start() {
+	s.logger.Info().Str("address", s.listener.Addr().String()).Msg("Starting admin server...")
+	if err := s.server.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.Error().Err(err).Msg("Error serving admin requests.")
+	}
+}
+
+func (s *Server) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("Error stopping admin server.")
+	}
+}
+
+func (s *Server) connections(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.simulator.Connections())
+}
+
+func (s *Server) requests(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.simulator.Requests())
+}
+
+func (s *Server) drain(w http.ResponseWriter, _ *http.Request) {
+	s.logger.Info().Msg("Draining requested through the admin API.")
+	go s.simulator.Stop()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type gracePeriod struct {
+	GracePeriod string `json:"gracePeriod"`
+}
+
+func (s *Server) gracePeriod(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(w, http.StatusOK, gracePeriod{GracePeriod: s.simulator.GracePeriod().String()})
+}
+
+func (s *Server) setGracePeriod(w http.ResponseWriter, r *http.Request) {
+	var body gracePeriod
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	period, err := time.ParseDuration(body.GracePeriod)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if period < 0 {
+		s.writeError(w, http.StatusBadRequest, errors.New("grace period can't be negative"))
+		return
+	}
+	s.simulator.SetGracePeriod(period)
+	s.logger.Info().Dur("gracePeriod", period).Msg("Grace period changed through the admin API.")
+	s.gracePeriod(w, r)
+}
+
+func (s *Server) setScenario(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	rules, err := scenario.Parse(data)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.simulator.SetScenario(rules)
+	s.logger.Info().Str("scenario", rules.Name).Msg("Scenario changed through the admin API.")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, err error) {
+	s.writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.logger.Error().Err(err).Msg("Error writing admin response.")
+	}
+}
@@ -0,0 +1,140 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"github.com/form3tech-oss/interview-simulator/internal/admin"
+	"github.com/form3tech-oss/interview-simulator/internal/metrics"
+	"github.com/form3tech-oss/interview-simulator/internal/mocks"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	tcp_listener "github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AdminTestSuite struct {
+	suite.Suite
+	simulator *mocks.MockSimulator
+	metrics   *metrics.Registry
+	server    *httptest.Server
+}
+
+func TestAdminSuite(t *testing.T) {
+	suite.Run(t, &AdminTestSuite{})
+}
+
+func (suite *AdminTestSuite) SetupTest() {
+	suite.simulator = &mocks.MockSimulator{}
+	suite.metrics = metrics.NewRegistry()
+	s, err := admin.New("localhost:0", suite.simulator, suite.metrics, zerolog.Nop())
+	suite.Require().NoError(err)
+	suite.server = httptest.NewServer(s.Handler())
+}
+
+func (suite *AdminTestSuite) TearDownTest() {
+	suite.server.Close()
+	suite.simulator.AssertExpectations(suite.T())
+}
+
+func (suite *AdminTestSuite) Test_ListConnections() {
+	acceptedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	suite.simulator.On("Connections").Return([]tcp_listener.ConnectionInfo{
+		{ID: 1, RemoteAddr: "127.0.0.1:5000", AcceptedAt: acceptedAt, Active: true},
+	})
+
+	resp, body := suite.do(http.MethodGet, "/connections", "")
+
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.JSONEq(`[{"id": 1, "listener": "", "remoteAddr": "127.0.0.1:5000", "acceptedAt": "2024-01-01T00:00:00Z", "active": true, "pipelined": false}]`, body)
+}
+
+func (suite *AdminTestSuite) Test_ListRequests() {
+	startedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	suite.simulator.On("Requests").Return([]tcp_listener.RequestInfo{
+		{ID: 3, ConnectionID: 1, Request: "PAYMENT|500", StartedAt: startedAt},
+	})
+
+	resp, body := suite.do(http.MethodGet, "/requests", "")
+
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.JSONEq(`[{"id": 3, "listener": "", "connectionId": 1, "request": "PAYMENT|500", "startedAt": "2024-01-01T00:00:00Z"}]`, body)
+}
+
+func (suite *AdminTestSuite) Test_Drain() {
+	stopped := make(chan struct{})
+	suite.simulator.On("Stop").Run(func(mock.Arguments) { close(stopped) }).Once()
+
+	resp, _ := suite.do(http.MethodPost, "/drain", "")
+
+	suite.Equal(http.StatusAccepted, resp.StatusCode)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		suite.Fail("Simulator was not stopped")
+	}
+}
+
+func (suite *AdminTestSuite) Test_SetGracePeriod() {
+	suite.simulator.On("SetGracePeriod", 3*time.Second).Once()
+	suite.simulator.On("GracePeriod").Return(3 * time.Second)
+
+	resp, body := suite.do(http.MethodPut, "/grace-period", `{"gracePeriod": "3s"}`)
+
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.JSONEq(`{"gracePeriod": "3s"}`, body)
+}
+
+func (suite *AdminTestSuite) Test_SetInvalidGracePeriod() {
+	for _, body := range []string{`{"gracePeriod": "soon"}`, `{"gracePeriod": "-1s"}`, `3s`} {
+		resp, _ := suite.do(http.MethodPut, "/grace-period", body)
+
+		suite.Equal(http.StatusBadRequest, resp.StatusCode, body)
+	}
+}
+
+func (suite *AdminTestSuite) Test_SetScenario() {
+	suite.simulator.On("SetScenario", mock.MatchedBy(func(s *scenario.Scenario) bool {
+		return s.Name == "rejections" && len(s.Rules) == 1
+	})).Once()
+
+	resp, _ := suite.do(http.MethodPut, "/scenario", "name: rejections\nrules: [{status: REJECTED, reason: Closed}]")
+
+	suite.Equal(http.StatusNoContent, resp.StatusCode)
+}
+
+func (suite *AdminTestSuite) Test_SetInvalidScenario() {
+	resp, body := suite.do(http.MethodPut, "/scenario", "rules: [{status: PENDING}]")
+
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+	var errBody map[string]string
+	suite.NoError(json.Unmarshal([]byte(body), &errBody))
+	suite.Contains(errBody["error"], "unknown status")
+}
+
+func (suite *AdminTestSuite) Test_Metrics() {
+	suite.metrics.NewCounter("simulator_test_total", "Test counter.").Inc()
+
+	resp, body := suite.do(http.MethodGet, "/metrics", "")
+
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal("text/plain; version=0.0.4; charset=utf-8", resp.Header.Get("Content-Type"))
+	suite.Equal("# HELP simulator_test_total Test counter.\n# TYPE simulator_test_total counter\nsimulator_test_total 1\n", body)
+}
+
+func (suite *AdminTestSuite) do(method string, path string, body string) (*http.Response, string) {
+	req, err := http.NewRequest(method, suite.server.URL+path, strings.NewReader(body))
+	suite.Require().NoError(err)
+	resp, err := http.DefaultClient.Do(req)
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	suite.Require().NoError(err)
+	return resp, string(data)
+}
@@ -0,0 +1,20 @@
+package clock
+
+import "time"
+
+// Clock abstracts the passing of time so delays and timeouts can be tested
+// deterministically.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+func (Real) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
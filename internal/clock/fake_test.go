@@ -0,0 +1,63 @@
+package clock_test
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/clock"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type FakeTestSuite struct {
+	suite.Suite
+	start time.Time
+	clock *clock.Fake
+}
+
+func TestFakeSuite(t *testing.T) {
+	suite.Run(t, &FakeTestSuite{})
+}
+
+func (suite *FakeTestSuite) SetupTest() {
+	suite.start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	suite.clock = clock.NewFake(suite.start)
+}
+
+func (suite *FakeTestSuite) Test_NowOnlyMovesWhenAdvanced() {
+	suite.Equal(suite.start, suite.clock.Now())
+
+	suite.clock.Advance(time.Second)
+
+	suite.Equal(suite.start.Add(time.Second), suite.clock.Now())
+}
+
+func (suite *FakeTestSuite) Test_AfterFiresWhenDue() {
+	short := suite.clock.After(time.Second)
+	long := suite.clock.After(time.Minute)
+
+	suite.clock.Advance(time.Second - time.Millisecond)
+	suite.Empty(short)
+	suite.Equal(2, suite.clock.Waiters())
+
+	suite.clock.Advance(time.Millisecond)
+	suite.Equal(suite.start.Add(time.Second), <-short)
+	suite.Empty(long)
+	suite.Equal(1, suite.clock.Waiters())
+}
+
+func (suite *FakeTestSuite) Test_AfterWithoutDurationFiresImmediately() {
+	suite.Equal(suite.start, <-suite.clock.After(0))
+	suite.Equal(0, suite.clock.Waiters())
+}
+
+func (suite *FakeTestSuite) Test_BlockUntilWaitsForWaiters() {
+	fired := make(chan time.Time)
+	go func() {
+		fired <- <-suite.clock.After(time.Second)
+	}()
+
+	suite.clock.BlockUntil(1)
+	suite.clock.Advance(time.Second)
+
+	suite.Equal(suite.start.Add(time.Second), <-fired)
+}
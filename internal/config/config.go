@@ -0,0 +1,348 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	"github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPort        = 8080
+	DefaultBindAddress = "localhost"
+	DefaultGracePeriod = 5 * time.Second
+	DefaultLogLevel    = "info"
+)
+
+// envPrefix prefixes the environment variables, e.g. SIMULATOR_PORT.
+const envPrefix = "SIMULATOR_"
+
+// Config is the configuration of the simulator. It is loaded from a YAML or JSON file,
+// then from the environment, then from the command-line flags, each overriding the
+// previous ones.
+type Config struct {
+	Port        int           `yaml:"port"`
+	BindAddress string        `yaml:"bindAddress"`
+	GracePeriod time.Duration `yaml:"gracePeriod"`
+	LogLevel    string        `yaml:"logLevel"`
+	// DelayThreshold is the amount above which the default scenario delays the payments.
+	DelayThreshold uint64 `yaml:"delayThreshold"`
+	// DelayCap caps the delays of the default scenario. Zero doesn't cap them.
+	DelayCap time.Duration `yaml:"delayCap"`
+	// Heartbeat is the interval of the heartbeats sent to idle connections. Zero disables
+	// them.
+	Heartbeat time.Duration `yaml:"heartbeat"`
+	// The timeouts of the connections. Zero doesn't limit them.
+	IdleTimeout  time.Duration `yaml:"idleTimeout"`
+	ReadTimeout  time.Duration `yaml:"readTimeout"`
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+	// MaxRequestSize is the maximum length of a request line, the longer ones being handled
+	// with the OversizedRequests policy.
+	MaxRequestSize    int    `yaml:"maxRequestSize"`
+	OversizedRequests string `yaml:"oversizedRequests"`
+	// MaxConnections is the maximum open connections of each listener, the others being
+	// handled with the ConnectionLimit policy. Zero doesn't limit them.
+	MaxConnections  int    `yaml:"maxConnections"`
+	ConnectionLimit string `yaml:"connectionLimit"`
+	// RateLimit and GlobalRateLimit are the requests per second allowed on each connection
+	// and each listener, the others being handled with the RateLimitPolicy. Zero doesn't
+	// limit them.
+	RateLimit       float64 `yaml:"rateLimit"`
+	GlobalRateLimit float64 `yaml:"globalRateLimit"`
+	RateLimitBurst  int     `yaml:"rateLimitBurst"`
+	RateLimitPolicy string  `yaml:"rateLimitPolicy"`
+	RateLimitReason string  `yaml:"rateLimitReason"`
+	// Participants is the file with the participants allowed to log on. Empty disables the
+	// sessions.
+	Participants string `yaml:"participants"`
+	// Sequencing numbers the requests and responses of each connection, handling the gaps
+	// with the SequenceGaps policy.
+	Sequencing        bool   `yaml:"sequencing"`
+	SequenceGaps      string `yaml:"sequenceGaps"`
+	SequenceGapReason string `yaml:"sequenceGapReason"`
+}
+
+func Default() Config {
+	return Config{
+		Port:              DefaultPort,
+		BindAddress:       DefaultBindAddress,
+		GracePeriod:       DefaultGracePeriod,
+		LogLevel:          DefaultLogLevel,
+		DelayThreshold:    scenario.DefaultDelayThreshold,
+		DelayCap:          scenario.DefaultMaxDelay,
+		MaxRequestSize:    tcp_listener.DefaultMaxRequestSize,
+		OversizedRequests: string(tcp_listener.OversizedReject),
+		ConnectionLimit:   string(tcp_listener.ConnectionsRefuse),
+		RateLimitBurst:    1,
+		RateLimitPolicy:   string(tcp_listener.RateLimitReject),
+		RateLimitReason:   tcp_listener.DefaultRateLimitReason,
+		SequenceGaps:      string(tcp_listener.GapsReject),
+		SequenceGapReason: tcp_listener.DefaultGapReason,
+	}
+}
+
+// Address is the address of the default listener.
+func (c Config) Address() string {
+	return fmt.Sprintf("%s:%d", c.BindAddress, c.Port)
+}
+
+// Level is the parsed log level, valid once the configuration is validated.
+func (c Config) Level() zerolog.Level {
+	level, _ := zerolog.ParseLevel(c.LogLevel)
+	return level
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	if c.Port < 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("invalid port %d: must be between 0 and 65535", c.Port))
+	}
+	if c.BindAddress == "" {
+		errs = append(errs, errors.New("invalid bind address: can't be empty"))
+	}
+	if c.GracePeriod < 0 {
+		errs = append(errs, fmt.Errorf("invalid grace period %s: can't be negative", c.GracePeriod))
+	}
+	if _, err := zerolog.ParseLevel(c.LogLevel); err != nil || c.LogLevel == "" {
+		errs = append(errs, fmt.Errorf("invalid log level %q: must be one of trace, debug, info, warn, error, fatal, panic or disabled", c.LogLevel))
+	}
+	if c.DelayCap < 0 {
+		errs = append(errs, fmt.Errorf("invalid delay cap %s: can't be negative", c.DelayCap))
+	}
+	if c.Heartbeat < 0 {
+		errs = append(errs, fmt.Errorf("invalid heartbeat %s: can't be negative", c.Heartbeat))
+	}
+	if c.IdleTimeout < 0 {
+		errs = append(errs, fmt.Errorf("invalid idle timeout %s: can't be negative", c.IdleTimeout))
+	}
+	if c.ReadTimeout < 0 {
+		errs = append(errs, fmt.Errorf("invalid read timeout %s: can't be negative", c.ReadTimeout))
+	}
+	if c.WriteTimeout < 0 {
+		errs = append(errs, fmt.Errorf("invalid write timeout %s: can't be negative", c.WriteTimeout))
+	}
+	if c.MaxRequestSize <= 0 {
+		errs = append(errs, fmt.Errorf("invalid max request size %d: must be positive", c.MaxRequestSize))
+	}
+	if c.MaxConnections < 0 {
+		errs = append(errs, fmt.Errorf("invalid max connections %d: can't be negative", c.MaxConnections))
+	}
+	if c.RateLimit < 0 {
+		errs = append(errs, fmt.Errorf("invalid rate limit %g: can't be negative", c.RateLimit))
+	}
+	if c.GlobalRateLimit < 0 {
+		errs = append(errs, fmt.Errorf("invalid global rate limit %g: can't be negative", c.GlobalRateLimit))
+	}
+	if c.RateLimitBurst < 1 {
+		errs = append(errs, fmt.Errorf("invalid rate limit burst %d: must be at least 1", c.RateLimitBurst))
+	}
+	errs = append(errs,
+		oneOf("oversized requests", c.OversizedRequests, tcp_listener.OversizedReject, tcp_listener.OversizedClose),
+		oneOf("connection limit", c.ConnectionLimit, tcp_listener.ConnectionsRefuse, tcp_listener.ConnectionsQueue, tcp_listener.ConnectionsReject),
+		oneOf("rate limit policy", c.RateLimitPolicy, tcp_listener.RateLimitReject, tcp_listener.RateLimitDelay),
+		oneOf("sequence gaps", c.SequenceGaps, tcp_listener.GapsReject, tcp_listener.GapsResend),
+	)
+	return errors.Join(errs...)
+}
+
+// oneOf checks that the value is one of the policies.
+func oneOf[P ~string](name string, value string, policies ...P) error {
+	names := make([]string, len(policies))
+	for i, policy := range policies {
+		if value == string(policy) {
+			return nil
+		}
+		names[i] = string(policy)
+	}
+	return fmt.Errorf("invalid %s %q: must be one of %s", name, value, strings.Join(names, ", "))
+}
+
+// LoadFile overrides the configuration with the fields set in a YAML or JSON file.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadEnv overrides the configuration with the environment variables set, looked up with
+// lookupEnv, e.g. os.LookupEnv.
+func (c *Config) LoadEnv(lookupEnv func(string) (string, bool)) error {
+	var errs []error
+	for _, field := range c.fields() {
+		name := envPrefix + field.env
+		if value, ok := lookupEnv(name); ok {
+			if err := field.value.Set(value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s %q: %w", name, value, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Flags are the command-line flags of the configuration, which override the file and
+// the environment when set.
+type Flags struct {
+	// File is the configuration file, if any.
+	File   string
+	values Config
+	set    *flag.FlagSet
+}
+
+func RegisterFlags(set *flag.FlagSet) *Flags {
+	f := &Flags{values: Default(), set: set}
+	set.StringVar(&f.File, "config", "", "YAML or JSON configuration file")
+	for _, field := range f.values.fields() {
+		switch v := field.value.(type) {
+		case boolValue:
+			set.BoolVar(v.p, field.flag, *v.p, field.usage)
+		case stringValue:
+			set.StringVar(v.p, field.flag, *v.p, field.usage)
+		case intValue:
+			set.IntVar(v.p, field.flag, *v.p, field.usage)
+		case uintValue:
+			set.Uint64Var(v.p, field.flag, *v.p, field.usage)
+		case floatValue:
+			set.Float64Var(v.p, field.flag, *v.p, field.usage)
+		case durationValue:
+			set.DurationVar(v.p, field.flag, *v.p, field.usage)
+		}
+	}
+	return f
+}
+
+// Load loads the configuration from the file, the environment and the flags set, and
+// validates it.
+func (f *Flags) Load(lookupEnv func(string) (string, bool)) (Config, error) {
+	c := Default()
+	if f.File != "" {
+		if err := c.LoadFile(f.File); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := c.LoadEnv(lookupEnv); err != nil {
+		return Config{}, err
+	}
+	fields := c.fields()
+	f.set.Visit(func(set *flag.Flag) {
+		for _, field := range fields {
+			if field.flag == set.Name {
+				// the flag was parsed already, so its value is valid
+				_ = field.value.Set(set.Value.String())
+			}
+		}
+	})
+	return c, c.Validate()
+}
+
+type field struct {
+	flag  string
+	env   string
+	usage string
+	value interface{ Set(string) error }
+}
+
+// fields binds the flags and environment variables to the fields of the configuration.
+func (c *Config) fields() []field {
+	return []field{
+		{"port", "PORT", "port of the default listener", intValue{&c.Port}},
+		{"bind", "BIND_ADDRESS", "address of the interface the default listener binds to", stringValue{&c.BindAddress}},
+		{"grace-period", "GRACE_PERIOD", "time to complete the in-flight requests when shutting down", durationValue{&c.GracePeriod}},
+		{"log-level", "LOG_LEVEL", "log level: trace, debug, info, warn or error", stringValue{&c.LogLevel}},
+		{"delay-threshold", "DELAY_THRESHOLD", "amount above which the default scenario delays the payments", uintValue{&c.DelayThreshold}},
+		{"delay-cap", "DELAY_CAP", "maximum delay of the default scenario, 0 for no cap", durationValue{&c.DelayCap}},
+		{"heartbeat", "HEARTBEAT", "interval of the heartbeats sent to idle connections, 0 to disable them", durationValue{&c.Heartbeat}},
+		{"idle-timeout", "IDLE_TIMEOUT", "time a connection can wait for a request before being closed, 0 for no limit", durationValue{&c.IdleTimeout}},
+		{"read-timeout", "READ_TIMEOUT", "time a client can take to send a request line, 0 for no limit", durationValue{&c.ReadTimeout}},
+		{"write-timeout", "WRITE_TIMEOUT", "time a client can take to receive a response, 0 for no limit", durationValue{&c.WriteTimeout}},
+		{"max-request-size", "MAX_REQUEST_SIZE", "maximum length of a request line in bytes", intValue{&c.MaxRequestSize}},
+		{"oversized-requests", "OVERSIZED_REQUESTS", "what to do with requests longer than the maximum size: reject or close", stringValue{&c.OversizedRequests}},
+		{"max-connections", "MAX_CONNECTIONS", "maximum open connections per listener, 0 for no limit", intValue{&c.MaxConnections}},
+		{"connection-limit", "CONNECTION_LIMIT", "what to do with connections over the maximum: refuse, queue or reject", stringValue{&c.ConnectionLimit}},
+		{"rate-limit", "RATE_LIMIT", "requests per second allowed on each connection, 0 for no limit", floatValue{&c.RateLimit}},
+		{"global-rate-limit", "GLOBAL_RATE_LIMIT", "requests per second allowed on each listener, 0 for no limit", floatValue{&c.GlobalRateLimit}},
+		{"rate-limit-burst", "RATE_LIMIT_BURST", "requests allowed at once by the rate limits", intValue{&c.RateLimitBurst}},
+		{"rate-limit-policy", "RATE_LIMIT_POLICY", "what to do with requests over the rate limits: reject or delay", stringValue{&c.RateLimitPolicy}},
+		{"rate-limit-reason", "RATE_LIMIT_REASON", "reason of the requests rejected by the rate limits", stringValue{&c.RateLimitReason}},
+		{"participants", "PARTICIPANTS", "YAML or JSON file with the participants allowed to log on, enables sessions", stringValue{&c.Participants}},
+		{"sequencing", "SEQUENCING", "number the requests and responses of each connection, as <sequence-number>|<line>", boolValue{&c.Sequencing}},
+		{"sequence-gaps", "SEQUENCE_GAPS", "what to do with requests after a gap in the sequence: reject or resend", stringValue{&c.SequenceGaps}},
+		{"sequence-gap-reason", "SEQUENCE_GAP_REASON", "reason of the requests rejected after a gap in the sequence", stringValue{&c.SequenceGapReason}},
+	}
+}
+
+type stringValue struct{ p *string }
+
+func (v stringValue) Set(s string) error {
+	*v.p = s
+	return nil
+}
+
+type boolValue struct{ p *bool }
+
+func (v boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return errors.New("not a boolean")
+	}
+	*v.p = b
+	return nil
+}
+
+type intValue struct{ p *int }
+
+func (v intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return errors.New("not an integer")
+	}
+	*v.p = n
+	return nil
+}
+
+type uintValue struct{ p *uint64 }
+
+func (v uintValue) Set(s string) error {
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return errors.New("not a positive integer")
+	}
+	*v.p = n
+	return nil
+}
+
+type floatValue struct{ p *float64 }
+
+func (v floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return errors.New("not a number")
+	}
+	*v.p = f
+	return nil
+}
+
+type durationValue struct{ p *time.Duration }
+
+func (v durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.New("not a duration, e.g. 5s")
+	}
+	*v.p = d
+	return nil
+}
@@ -0,0 +1,175 @@
+package config_test
+
+import (
+	"flag"
+	"github.com/form3tech-oss/interview-simulator/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfigTestSuite struct {
+	suite.Suite
+	env map[string]string
+}
+
+func TestConfigSuite(t *testing.T) {
+	suite.Run(t, &ConfigTestSuite{})
+}
+
+func (suite *ConfigTestSuite) SetupTest() {
+	suite.env = map[string]string{}
+}
+
+func (suite *ConfigTestSuite) lookupEnv(name string) (string, bool) {
+	value, ok := suite.env[name]
+	return value, ok
+}
+
+func (suite *ConfigTestSuite) writeFile(name string, content string) string {
+	path := filepath.Join(suite.T().TempDir(), name)
+	suite.Require().NoError(os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func (suite *ConfigTestSuite) load(args ...string) (config.Config, error) {
+	set := flag.NewFlagSet("simulator", flag.ContinueOnError)
+	flags := config.RegisterFlags(set)
+	suite.Require().NoError(set.Parse(args))
+	return flags.Load(suite.lookupEnv)
+}
+
+func (suite *ConfigTestSuite) Test_Defaults() {
+	c, err := suite.load()
+
+	suite.Require().NoError(err)
+	suite.Equal(config.Default(), c)
+	suite.Equal("localhost:8080", c.Address())
+	suite.Equal(5*time.Second, c.GracePeriod)
+}
+
+func (suite *ConfigTestSuite) Test_Precedence() {
+	path := suite.writeFile("config.yaml", `
+port: 9000
+bindAddress: 0.0.0.0
+gracePeriod: 1s
+logLevel: debug
+delayThreshold: 500
+heartbeat: 10s
+maxConnections: 10
+rateLimit: 2.5
+`)
+	suite.env["SIMULATOR_GRACE_PERIOD"] = "2s"
+	suite.env["SIMULATOR_LOG_LEVEL"] = "warn"
+	suite.env["SIMULATOR_HEARTBEAT"] = "20s"
+	suite.env["SIMULATOR_MAX_CONNECTIONS"] = "20"
+	suite.env["SIMULATOR_RATE_LIMIT_POLICY"] = "delay"
+
+	c, err := suite.load("-config", path, "-log-level", "error", "-delay-cap", "0s", "-heartbeat", "30s", "-idle-timeout", "1m")
+
+	suite.Require().NoError(err)
+	suite.Equal(config.Config{
+		Port:              9000,
+		BindAddress:       "0.0.0.0",
+		GracePeriod:       2 * time.Second,
+		LogLevel:          "error",
+		DelayThreshold:    500,
+		DelayCap:          0,
+		Heartbeat:         30 * time.Second,
+		IdleTimeout:       time.Minute,
+		MaxRequestSize:    65536,
+		OversizedRequests: "reject",
+		MaxConnections:    20,
+		ConnectionLimit:   "refuse",
+		RateLimit:         2.5,
+		RateLimitBurst:    1,
+		RateLimitPolicy:   "delay",
+		RateLimitReason:   "Rate limit exceeded",
+		SequenceGaps:      "reject",
+		SequenceGapReason: "Sequence gap",
+	}, c)
+}
+
+func (suite *ConfigTestSuite) Test_JSONFile() {
+	path := suite.writeFile("config.json", `{"port": 9001, "gracePeriod": "250ms"}`)
+
+	c, err := suite.load("-config", path)
+
+	suite.Require().NoError(err)
+	suite.Equal(9001, c.Port)
+	suite.Equal(250*time.Millisecond, c.GracePeriod)
+}
+
+func (suite *ConfigTestSuite) Test_Participants() {
+	path := suite.writeFile("config.yaml", "participants: file.yaml\n")
+
+	c, err := suite.load("-config", path)
+	suite.Require().NoError(err)
+	suite.Equal("file.yaml", c.Participants)
+
+	suite.env["SIMULATOR_PARTICIPANTS"] = "env.yaml"
+	c, err = suite.load("-config", path)
+	suite.Require().NoError(err)
+	suite.Equal("env.yaml", c.Participants)
+}
+
+func (suite *ConfigTestSuite) Test_Sequencing() {
+	path := suite.writeFile("config.yaml", "sequencing: true\nsequenceGaps: resend\n")
+	suite.env["SIMULATOR_SEQUENCE_GAP_REASON"] = "Out of sequence"
+
+	c, err := suite.load("-config", path)
+	suite.Require().NoError(err)
+	suite.True(c.Sequencing)
+	suite.Equal("resend", c.SequenceGaps)
+	suite.Equal("Out of sequence", c.SequenceGapReason)
+
+	c, err = suite.load("-config", path, "-sequencing=false")
+	suite.Require().NoError(err)
+	suite.False(c.Sequencing)
+}
+
+func (suite *ConfigTestSuite) Test_UnknownFieldInFile() {
+	path := suite.writeFile("config.yaml", "prot: 9000\n")
+
+	_, err := suite.load("-config", path)
+
+	suite.ErrorContains(err, "field prot not found")
+}
+
+func (suite *ConfigTestSuite) Test_InvalidEnvironment() {
+	suite.env["SIMULATOR_PORT"] = "http"
+	suite.env["SIMULATOR_DELAY_CAP"] = "10"
+	suite.env["SIMULATOR_SEQUENCING"] = "maybe"
+	suite.env["SIMULATOR_RATE_LIMIT"] = "fast"
+
+	_, err := suite.load()
+
+	suite.ErrorContains(err, `invalid SIMULATOR_PORT "http": not an integer`)
+	suite.ErrorContains(err, `invalid SIMULATOR_DELAY_CAP "10": not a duration`)
+	suite.ErrorContains(err, `invalid SIMULATOR_SEQUENCING "maybe": not a boolean`)
+	suite.ErrorContains(err, `invalid SIMULATOR_RATE_LIMIT "fast": not a number`)
+}
+
+func (suite *ConfigTestSuite) Test_Validate() {
+	_, err := suite.load("-port", "70000", "-grace-period", "-1s", "-log-level", "loud", "-bind", "", "-heartbeat", "-1s",
+		"-read-timeout", "-1s", "-max-request-size", "0", "-max-connections", "-1", "-global-rate-limit", "-1", "-rate-limit-burst", "0",
+		"-oversized-requests", "truncate", "-connection-limit", "drop", "-rate-limit-policy", "queue", "-sequence-gaps", "ignore")
+
+	suite.ErrorContains(err, "invalid port 70000: must be between 0 and 65535")
+	suite.ErrorContains(err, "invalid grace period -1s: can't be negative")
+	suite.ErrorContains(err, `invalid log level "loud"`)
+	suite.ErrorContains(err, "invalid bind address: can't be empty")
+	suite.ErrorContains(err, "invalid heartbeat -1s: can't be negative")
+	suite.ErrorContains(err, "invalid read timeout -1s: can't be negative")
+	suite.ErrorContains(err, "invalid max request size 0: must be positive")
+	suite.ErrorContains(err, "invalid max connections -1: can't be negative")
+	suite.ErrorContains(err, "invalid global rate limit -1: can't be negative")
+	suite.ErrorContains(err, "invalid rate limit burst 0: must be at least 1")
+	suite.ErrorContains(err, `invalid oversized requests "truncate": must be one of reject, close`)
+	suite.ErrorContains(err, `invalid connection limit "drop": must be one of refuse, queue, reject`)
+	suite.ErrorContains(err, `invalid rate limit policy "queue": must be one of reject, delay`)
+	suite.ErrorContains(err, `invalid sequence gaps "ignore": must be one of reject, resend`)
+}
@@ -0,0 +1,109 @@
+package coordinator
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	tcp_listener "github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"sync"
+	"time"
+)
+
+// ListenerConfig configures one of the listeners of the simulator, e.g. one per
+// participant bank.
+type ListenerConfig struct {
+	Address     string
+	GracePeriod time.Duration
+	// Scenario of the listener. Defaults to scenario.Default.
+	Scenario *scenario.Scenario
+}
+
+// Coordinator runs several listeners and shuts them down together.
+type Coordinator struct {
+	listeners []*tcp_listener.TcpListener
+}
+
+// New creates a listener for each configuration. The options and deps are shared by all
+// the listeners, except for the scenario.
+func New(configs []ListenerConfig, options tcp_listener.Options, deps *tcp_listener.TcpListenerDeps) (*Coordinator, error) {
+	c := &Coordinator{}
+	for _, config := range configs {
+		listenerDeps := *deps
+		listenerDeps.Scenario = config.Scenario
+		listener, err := tcp_listener.New(config.Address, config.GracePeriod, options, &listenerDeps)
+		if err != nil {
+			c.Stop()
+			return nil, err
+		}
+		c.listeners = append(c.listeners, listener)
+	}
+	return c, nil
+}
+
+func (c *Coordinator) Listeners() []*tcp_listener.TcpListener {
+	return c.listeners
+}
+
+// Start accepts connections on all the listeners until they are stopped.
+func (c *Coordinator) Start() {
+	var wg sync.WaitGroup
+	for _, listener := range c.listeners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			listener.Start()
+		}()
+	}
+	wg.Wait()
+}
+
+// Stop stops all the listeners concurrently, so each one drains its requests within its
+// own grace period.
+func (c *Coordinator) Stop() {
+	var wg sync.WaitGroup
+	for _, listener := range c.listeners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			listener.Stop()
+		}()
+	}
+	wg.Wait()
+}
+
+func (c *Coordinator) Connections() []tcp_listener.ConnectionInfo {
+	connections := []tcp_listener.ConnectionInfo{}
+	for _, listener := range c.listeners {
+		connections = append(connections, listener.Connections()...)
+	}
+	return connections
+}
+
+func (c *Coordinator) Requests() []tcp_listener.RequestInfo {
+	requests := []tcp_listener.RequestInfo{}
+	for _, listener := range c.listeners {
+		requests = append(requests, listener.Requests()...)
+	}
+	return requests
+}
+
+// GracePeriod returns the longest grace period of the listeners.
+func (c *Coordinator) GracePeriod() time.Duration {
+	var longest time.Duration
+	for _, listener := range c.listeners {
+		longest = max(longest, listener.GracePeriod())
+	}
+	return longest
+}
+
+// SetGracePeriod changes the grace period of all the listeners.
+func (c *Coordinator) SetGracePeriod(period time.Duration) {
+	for _, listener := range c.listeners {
+		listener.SetGracePeriod(period)
+	}
+}
+
+// SetScenario replaces the scenario of all the listeners.
+func (c *Coordinator) SetScenario(s *scenario.Scenario) {
+	for _, listener := range c.listeners {
+		listener.SetScenario(s)
+	}
+}
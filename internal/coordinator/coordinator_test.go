@@ -0,0 +1,119 @@
+package coordinator_test
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/coordinator"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	tcp_listener "github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"github.com/rs/zerolog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CoordinatorTestSuite struct {
+	suite.Suite
+	coordinator *coordinator.Coordinator
+	addresses   []string
+}
+
+func TestCoordinatorSuite(t *testing.T) {
+	suite.Run(t, &CoordinatorTestSuite{})
+}
+
+func (suite *CoordinatorTestSuite) SetupTest() {
+	closed, err := scenario.Parse([]byte(`rules: [{amounts: [10], status: REJECTED, reason: Participant closed}, {amount: {min: 101}, delay: amount}]`))
+	suite.Require().NoError(err)
+
+	suite.coordinator, err = coordinator.New([]coordinator.ListenerConfig{
+		{Address: "localhost:0", GracePeriod: 200 * time.Millisecond},
+		{Address: "127.0.0.1:0", GracePeriod: 500 * time.Millisecond, Scenario: closed},
+	}, tcp_listener.Options{}, &tcp_listener.TcpListenerDeps{Logger: zerolog.Nop(), Listener: tcp_listener.NetListener{}, NewScanner: tcp_listener.BufioScanner{}})
+	suite.Require().NoError(err)
+
+	suite.addresses = nil
+	for _, listener := range suite.coordinator.Listeners() {
+		suite.addresses = append(suite.addresses, listener.Addr().String())
+	}
+	go suite.coordinator.Start()
+}
+
+func (suite *CoordinatorTestSuite) TearDownTest() {
+	suite.coordinator.Stop()
+}
+
+func (suite *CoordinatorTestSuite) Test_EachListenerUsesItsScenario() {
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", suite.send(suite.addresses[0], "PAYMENT|10"))
+	suite.Equal("RESPONSE|REJECTED|Participant closed", suite.send(suite.addresses[1], "PAYMENT|10"))
+}
+
+func (suite *CoordinatorTestSuite) Test_StopDrainsEachListenerWithinItsGracePeriod() {
+	responses := make([]chan string, len(suite.addresses))
+	for i, address := range suite.addresses {
+		responses[i] = make(chan string, 1)
+		go func() { responses[i] <- suite.send(address, "PAYMENT|50000") }()
+	}
+
+	// wait for the requests to be accepted
+	time.Sleep(100 * time.Millisecond)
+	suite.Len(suite.coordinator.Requests(), 2)
+
+	start := time.Now()
+	suite.coordinator.Stop()
+	duration := time.Since(start)
+
+	suite.Equal("RESPONSE|REJECTED|Cancelled", <-responses[0])
+	suite.Equal("RESPONSE|REJECTED|Cancelled", <-responses[1])
+	suite.GreaterOrEqual(duration, 500*time.Millisecond, "Stopped before the longest grace period")
+	suite.LessOrEqual(duration, 550*time.Millisecond, "Listeners were not stopped concurrently")
+}
+
+func (suite *CoordinatorTestSuite) Test_ConnectionsOfAllListeners() {
+	for _, address := range suite.addresses {
+		conn, err := net.Dial("tcp", address)
+		suite.Require().NoError(err)
+		defer conn.Close()
+	}
+
+	// wait for the connections to be accepted
+	time.Sleep(100 * time.Millisecond)
+	connections := suite.coordinator.Connections()
+
+	suite.Require().Len(connections, 2)
+	suite.ElementsMatch(suite.addresses, []string{connections[0].Listener, connections[1].Listener})
+}
+
+func (suite *CoordinatorTestSuite) Test_GracePeriod() {
+	suite.Equal(500*time.Millisecond, suite.coordinator.GracePeriod())
+
+	suite.coordinator.SetGracePeriod(time.Second)
+
+	for _, listener := range suite.coordinator.Listeners() {
+		suite.Equal(time.Second, listener.GracePeriod())
+	}
+}
+
+func (suite *CoordinatorTestSuite) Test_FailingListenerStopsTheOthers() {
+	_, err := coordinator.New([]coordinator.ListenerConfig{
+		{Address: "localhost:0"},
+		{Address: suite.addresses[0]},
+	}, tcp_listener.Options{}, &tcp_listener.TcpListenerDeps{Logger: zerolog.Nop(), Listener: tcp_listener.NetListener{}, NewScanner: tcp_listener.BufioScanner{}})
+
+	suite.Error(err)
+}
+
+func (suite *CoordinatorTestSuite) send(address string, request string) string {
+	conn, err := net.Dial("tcp", address)
+	suite.Require().NoError(err)
+	defer conn.Close()
+
+	_, err = fmt.Fprintf(conn, "%s\n", request)
+	suite.Require().NoError(err)
+	response, err := bufio.NewReader(conn).ReadString('\n')
+	suite.Require().NoError(err)
+	return strings.TrimSpace(response)
+}
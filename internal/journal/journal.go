@@ -0,0 +1,89 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry is a request handled by the simulator and the response sent for it.
+type Entry struct {
+	Listener          string    `json:"listener,omitempty"`
+	ConnectionID      uint64    `json:"connectionId"`
+	Participant       string    `json:"participant,omitempty"`
+	Request           string    `json:"request"`
+	Response          string    `json:"response"`
+	Fault             string    `json:"fault,omitempty"`
+	ReceivedAt        time.Time `json:"receivedAt"`
+	RespondedAt       time.Time `json:"respondedAt"`
+	ProcessingDelayMs int64     `json:"processingDelayMs"`
+}
+
+func NewEntry(connectionID uint64, request string, response string, fault string, receivedAt time.Time, respondedAt time.Time) Entry {
+	return Entry{
+		ConnectionID:      connectionID,
+		Request:           request,
+		Response:          response,
+		Fault:             fault,
+		ReceivedAt:        receivedAt,
+		RespondedAt:       respondedAt,
+		ProcessingDelayMs: respondedAt.Sub(receivedAt).Milliseconds(),
+	}
+}
+
+// File is an append-only journal storing one JSON entry per line.
+type File struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func Open(path string) (*File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &File{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+func (f *File) Record(entry Entry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.encoder.Encode(entry)
+}
+
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// Read parses the entries of a journal.
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func ReadFile(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(file)
+}
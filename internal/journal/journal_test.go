@@ -0,0 +1,187 @@
+package journal_test
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/journal"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	tcp_listener "github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"github.com/rs/zerolog"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type JournalTestSuite struct {
+	suite.Suite
+	path string
+}
+
+func TestJournalSuite(t *testing.T) {
+	suite.Run(t, &JournalTestSuite{})
+}
+
+func (suite *JournalTestSuite) SetupTest() {
+	suite.path = filepath.Join(suite.T().TempDir(), "journal.jsonl")
+}
+
+func (suite *JournalTestSuite) Test_RecordsHandledRequests() {
+	file, err := journal.Open(suite.path)
+	suite.Require().NoError(err)
+	address := suite.startSimulator(scenario.Default(), file)
+
+	conn, err := net.Dial("tcp", address)
+	suite.Require().NoError(err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for _, request := range []string{"PAYMENT|10", "PAYMENT|150", "PAYMENT|abc"} {
+		_, err = fmt.Fprintf(conn, "%s\n", request)
+		suite.Require().NoError(err)
+		_, err = reader.ReadString('\n')
+		suite.Require().NoError(err)
+	}
+	suite.Require().NoError(file.Close())
+
+	entries, err := journal.ReadFile(suite.path)
+
+	suite.Require().NoError(err)
+	suite.Require().Len(entries, 3)
+	suite.Equal("PAYMENT|10", entries[0].Request)
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", entries[0].Response)
+	suite.Equal("PAYMENT|150", entries[1].Request)
+	suite.GreaterOrEqual(entries[1].ProcessingDelayMs, int64(150))
+	suite.Equal("RESPONSE|REJECTED|Invalid amount", entries[2].Response)
+	for _, entry := range entries {
+		suite.Equal(entries[0].ConnectionID, entry.ConnectionID)
+		suite.False(entry.RespondedAt.Before(entry.ReceivedAt))
+	}
+}
+
+func (suite *JournalTestSuite) Test_MemoryWaitsForEntries() {
+	memory := journal.NewMemory()
+	go func() {
+		for i := range 3 {
+			_ = memory.Record(journal.Entry{ConnectionID: uint64(i)})
+		}
+	}()
+
+	entries, ok := memory.Wait(3, time.Second)
+
+	suite.True(ok)
+	suite.Len(entries, 3)
+	_, ok = memory.Wait(4, 10*time.Millisecond)
+	suite.False(ok, "Wait should time out")
+}
+
+func (suite *JournalTestSuite) Test_ReplayReportsDifferentResponses() {
+	rules, err := scenario.Parse([]byte(`rules: [{amounts: [20], status: REJECTED, reason: Insufficient funds}]`))
+	suite.Require().NoError(err)
+	address := suite.startSimulator(rules, nil)
+	entries := []journal.Entry{
+		{ConnectionID: 1, Request: "PAYMENT|10", Response: "RESPONSE|ACCEPTED|Transaction processed"},
+		{ConnectionID: 1, Request: "PAYMENT|20", Response: "RESPONSE|ACCEPTED|Transaction processed"},
+		{ConnectionID: 2, Request: "PAYMENT|x", Response: "RESPONSE|REJECTED|Invalid amount"},
+		{ConnectionID: 2, Request: "PAYMENT|30", Response: "", Fault: string(scenario.FaultDrop)},
+	}
+
+	results := journal.Replay(address, entries, journal.ReplayOptions{Timeout: time.Second})
+
+	suite.Require().Len(results, 4)
+	suite.True(results[0].Matches())
+	suite.False(results[1].Matches())
+	suite.Equal("RESPONSE|REJECTED|Insufficient funds", results[1].Actual)
+	suite.True(results[2].Matches())
+	suite.True(results[3].Matches(), "Entries recorded with a fault are not compared")
+}
+
+func (suite *JournalTestSuite) Test_ReplayIgnoresEchoTimestamps() {
+	address := suite.startSimulator(scenario.Default(), nil)
+	entries := []journal.Entry{
+		{ConnectionID: 1, Request: "ECHO", Response: "RESPONSE|ECHO|2024-01-01T00:00:00Z"},
+		{ConnectionID: 1, Request: "PIPELINE", Response: "RESPONSE|ACCEPTED|Pipelining enabled"},
+		{ConnectionID: 1, Request: "a|ECHO", Response: "a|RESPONSE|ECHO|2024-01-01T00:00:00Z"},
+		{ConnectionID: 1, Request: "b|ECHO", Response: "a|RESPONSE|ECHO|2024-01-01T00:00:00Z"},
+	}
+
+	results := journal.Replay(address, entries, journal.ReplayOptions{Timeout: time.Second})
+
+	suite.Require().Len(results, 4)
+	suite.True(results[0].Matches())
+	suite.True(results[2].Matches())
+	suite.False(results[3].Matches(), "Correlation ids should still be compared")
+}
+
+func (suite *JournalTestSuite) Test_ReplayAcknowledgesHeartbeats() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	defer listener.Close()
+	// sends a heartbeat before each response, which must be acknowledged
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			if _, err := reader.ReadString('\n'); err != nil {
+				return
+			}
+			fmt.Fprintf(conn, "HEARTBEAT|2024-01-01T00:00:00Z\n")
+			if ack, err := reader.ReadString('\n'); err != nil || ack != "HEARTBEAT\n" {
+				return
+			}
+			fmt.Fprintf(conn, "RESPONSE|ACCEPTED|Transaction processed\n")
+		}
+	}()
+	entries := []journal.Entry{
+		{ConnectionID: 1, Request: "PAYMENT|10", Response: "RESPONSE|ACCEPTED|Transaction processed"},
+		{ConnectionID: 1, Request: "PAYMENT|20", Response: "RESPONSE|ACCEPTED|Transaction processed"},
+	}
+
+	results := journal.Replay(listener.Addr().String(), entries, journal.ReplayOptions{Timeout: time.Second})
+
+	suite.Require().Len(results, 2)
+	suite.True(results[0].Matches(), "%+v", results[0])
+	suite.True(results[1].Matches(), "%+v", results[1])
+}
+
+func (suite *JournalTestSuite) Test_ReplayReportsUnreachableTarget() {
+	entries := []journal.Entry{{ConnectionID: 1, Request: "PAYMENT|10", Response: "RESPONSE|ACCEPTED|Transaction processed"}}
+
+	results := journal.Replay("localhost:1", entries, journal.ReplayOptions{Timeout: time.Second})
+
+	suite.Require().Len(results, 1)
+	suite.Error(results[0].Err)
+	suite.False(results[0].Matches())
+}
+
+func (suite *JournalTestSuite) Test_ReadSkipsEmptyLinesAndRejectsInvalidOnes() {
+	entries, err := journal.Read(strings.NewReader("{\"connectionId\": 1, \"request\": \"PAYMENT|1\"}\n\n"))
+	suite.NoError(err)
+	suite.Len(entries, 1)
+
+	_, err = journal.Read(strings.NewReader("not json\n"))
+	suite.Error(err)
+}
+
+func (suite *JournalTestSuite) startSimulator(rules *scenario.Scenario, file *journal.File) string {
+	deps := &tcp_listener.TcpListenerDeps{
+		Logger:     zerolog.Nop(),
+		Listener:   tcp_listener.NetListener{},
+		NewScanner: tcp_listener.BufioScanner{},
+		Scenario:   rules,
+	}
+	if file != nil {
+		deps.Journal = file
+	}
+	listener, err := tcp_listener.New("localhost:0", time.Second, tcp_listener.Options{}, deps)
+	suite.Require().NoError(err)
+	go listener.Start()
+	suite.T().Cleanup(listener.Stop)
+	return listener.Addr().String()
+}
@@ -0,0 +1,54 @@
+package journal
+
+import (
+	"sync"
+	"time"
+)
+
+// Memory is a journal keeping the entries in memory, for tests.
+type Memory struct {
+	mu      sync.Mutex
+	entries []Entry
+	// updated is closed and replaced when an entry is recorded.
+	updated chan struct{}
+}
+
+func NewMemory() *Memory {
+	return &Memory{updated: make(chan struct{})}
+}
+
+func (m *Memory) Record(entry Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, entry)
+	close(m.updated)
+	m.updated = make(chan struct{})
+	return nil
+}
+
+// Entries returns the entries recorded so far.
+func (m *Memory) Entries() []Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Entry(nil), m.entries...)
+}
+
+// Wait waits for at least n entries to be recorded and returns them, or the entries
+// recorded so far and false after the timeout.
+func (m *Memory) Wait(n int, timeout time.Duration) ([]Entry, bool) {
+	deadline := time.After(timeout)
+	for {
+		m.mu.Lock()
+		entries := append([]Entry(nil), m.entries...)
+		updated := m.updated
+		m.mu.Unlock()
+		if len(entries) >= n {
+			return entries, true
+		}
+		select {
+		case <-updated:
+		case <-deadline:
+			return entries, false
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package journal
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of replaying a journal entry.
+type Result struct {
+	Entry  Entry
+	Actual string
+	Err    error
+}
+
+const (
+	// echoResponse prefixes the responses to ECHO, followed by the time of the target.
+	echoResponse = "RESPONSE|ECHO|"
+	// heartbeat prefixes the heartbeats sent by the target, which are acknowledged with
+	// heartbeat alone.
+	heartbeat = "HEARTBEAT"
+)
+
+// Matches reports whether the target responded as recorded. Entries recorded with a
+// fault are not compared, as the fault is injected by the simulator, nor are the
+// timestamps of the echo responses.
+func (r Result) Matches() bool {
+	if r.Entry.Fault != "" {
+		return true
+	}
+	return r.Err == nil && withoutTimestamp(r.Actual) == withoutTimestamp(r.Entry.Response)
+}
+
+func withoutTimestamp(response string) string {
+	if before, _, found := strings.Cut(response, echoResponse); found {
+		return before + echoResponse
+	}
+	return response
+}
+
+// ReplayOptions configure how a journal is replayed.
+type ReplayOptions struct {
+	// Timeout of each request.
+	Timeout time.Duration
+	// Sequencing numbers the requests sent on each connection from 1, and strips the
+	// sequence numbers of the lines received, for journals recorded with sequencing.
+	Sequencing bool
+}
+
+// Replay re-sends the entries to the target. Entries recorded on the same connection are
+// sent in order over one connection, and connections are replayed concurrently. The
+// results are returned in the order of the entries.
+func Replay(target string, entries []Entry, options ReplayOptions) []Result {
+	type connectionKey struct {
+		listener string
+		id       uint64
+	}
+	results := make([]Result, len(entries))
+	connections := make(map[connectionKey][]int)
+	var order []connectionKey
+	for i, entry := range entries {
+		key := connectionKey{listener: entry.Listener, id: entry.ConnectionID}
+		if _, ok := connections[key]; !ok {
+			order = append(order, key)
+		}
+		connections[key] = append(connections[key], i)
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range order {
+		wg.Add(1)
+		go func(indexes []int) {
+			defer wg.Done()
+			replayConnection(target, entries, indexes, results, options)
+		}(connections[id])
+	}
+	wg.Wait()
+	return results
+}
+
+func replayConnection(target string, entries []Entry, indexes []int, results []Result, options ReplayOptions) {
+	var conn *replayConn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for _, i := range indexes {
+		results[i].Entry = entries[i]
+		if conn == nil {
+			netConn, err := net.DialTimeout("tcp", target, options.Timeout)
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			conn = &replayConn{Conn: netConn, reader: bufio.NewReader(netConn), sequencing: options.Sequencing}
+		}
+
+		actual, err := conn.exchange(entries[i].Request, options.Timeout)
+		results[i].Actual = actual
+		results[i].Err = err
+		if err != nil {
+			// the target dropped or broke the connection, the next entries use a new one
+			conn.Close()
+			conn = nil
+		}
+	}
+}
+
+// replayConn is a connection to the target, numbering the lines sent with sequencing.
+type replayConn struct {
+	net.Conn
+	reader     *bufio.Reader
+	sequencing bool
+	sent       uint64
+}
+
+// exchange sends the request and returns its response, acknowledging the heartbeats
+// received meanwhile.
+func (c *replayConn) exchange(request string, timeout time.Duration) (string, error) {
+	if err := c.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return "", err
+	}
+	if err := c.send(request); err != nil {
+		return "", err
+	}
+	for {
+		response, err := c.receive()
+		if err != nil {
+			return "", err
+		}
+		if !strings.HasPrefix(response, heartbeat+"|") {
+			return response, nil
+		}
+		if err := c.send(heartbeat); err != nil {
+			return "", err
+		}
+	}
+}
+
+func (c *replayConn) send(line string) error {
+	if c.sequencing {
+		c.sent++
+		line = strconv.FormatUint(c.sent, 10) + "|" + line
+	}
+	_, err := fmt.Fprintf(c, "%s\n", line)
+	return err
+}
+
+func (c *replayConn) receive() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	if c.sequencing {
+		_, line, _ = strings.Cut(line, "|")
+	}
+	return line, nil
+}
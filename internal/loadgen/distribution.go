@@ -0,0 +1,74 @@
+package loadgen
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+type bucket struct {
+	min    uint64
+	max    uint64
+	weight float64
+}
+
+// Distribution picks the amounts of the payments.
+type Distribution struct {
+	buckets []bucket
+	total   float64
+}
+
+// ParseDistribution parses a comma separated list of amounts or amount ranges, each with an
+// optional weight, e.g. "10", "1-1000" or "10=80,101-10000=15,20000=5". Amounts are picked
+// uniformly within a range, and ranges are picked proportionally to their weight, 1 by
+// default.
+func ParseDistribution(spec string) (*Distribution, error) {
+	d := &Distribution{}
+	for _, part := range strings.Split(spec, ",") {
+		b := bucket{weight: 1}
+		amounts, weight, hasWeight := strings.Cut(strings.TrimSpace(part), "=")
+		if hasWeight {
+			w, err := strconv.ParseFloat(weight, 64)
+			if err != nil || w <= 0 {
+				return nil, fmt.Errorf("invalid weight %q", weight)
+			}
+			b.weight = w
+		}
+		low, high, isRange := strings.Cut(amounts, "-")
+		var err error
+		if b.min, err = strconv.ParseUint(low, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid amount %q", low)
+		}
+		b.max = b.min
+		if isRange {
+			if b.max, err = strconv.ParseUint(high, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid amount %q", high)
+			}
+			if b.max < b.min {
+				return nil, fmt.Errorf("invalid range %q", amounts)
+			}
+		}
+		d.buckets = append(d.buckets, b)
+		d.total += b.weight
+	}
+	return d, nil
+}
+
+func (d *Distribution) Amount(r *rand.Rand) uint64 {
+	pick := r.Float64() * d.total
+	b := d.buckets[len(d.buckets)-1]
+	for _, candidate := range d.buckets {
+		if pick < candidate.weight {
+			b = candidate
+			break
+		}
+		pick -= candidate.weight
+	}
+	// the number of amounts of the full range doesn't fit in an uint64
+	if b.min == 0 && b.max == math.MaxUint64 {
+		return r.Uint64()
+	}
+	return b.min + r.Uint64N(b.max-b.min+1)
+}
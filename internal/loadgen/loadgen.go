@@ -0,0 +1,152 @@
+// Package loadgen sends payments to the simulator, or a scheme endpoint, at a target rate
+// over many connections and reports the latency and outcome of the requests.
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"github.com/form3tech-oss/interview-simulator/client"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	Target      string
+	Connections int
+	// Rate is the number of payments per second across all the connections. Zero sends
+	// them as fast as the connections allow.
+	Rate float64
+	// Duration stops sending payments after the given time. Optional if Requests is set.
+	Duration time.Duration
+	// Requests stops sending payments after the given number. Optional if Duration is set.
+	Requests int
+	Amounts  *Distribution
+	// Timeout bounds each payment. Defaults to client.DefaultTimeout.
+	Timeout time.Duration
+}
+
+type result struct {
+	latency time.Duration
+	status  string
+	err     error
+}
+
+// Run opens the connections, sends the payments until the duration elapses, the number of
+// requests is sent or the context is cancelled, and waits for the in-flight payments to
+// complete.
+func Run(ctx context.Context, config Config) Report {
+	clients := connect(ctx, config)
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+
+	start := time.Now()
+	ticks := pace(ctx, config)
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+			for range ticks {
+				sent := time.Now()
+				resp, err := c.SendPayment(context.Background(), config.Amounts.Amount(r))
+				results <- result{latency: time.Since(sent), status: resp.Status, err: err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	report := Report{Statuses: make(map[string]int)}
+	var latencies []time.Duration
+	for result := range results {
+		report.Requests++
+		switch {
+		case errors.Is(result.err, client.ErrConnectionDropped):
+			report.Dropped++
+		case result.err != nil:
+			report.Errors++
+		default:
+			report.Statuses[result.status]++
+			latencies = append(latencies, result.latency)
+		}
+	}
+	report.Duration = time.Since(start)
+	report.Throughput = float64(report.Requests) / report.Duration.Seconds()
+	report.Latency = percentiles(latencies)
+	return report
+}
+
+// connect opens a dedicated connection for each worker, so the target sees all the
+// connections whatever the rate. A connection failing to open is dialled again by the
+// first payment of its worker, which reports the error.
+func connect(ctx context.Context, config Config) []*client.Client {
+	clients := make([]*client.Client, config.Connections)
+	var wg sync.WaitGroup
+	for i := range clients {
+		clients[i] = client.New(config.Target, client.Options{PoolSize: 1, Timeout: config.Timeout})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = clients[i].Connect(ctx)
+		}()
+	}
+	wg.Wait()
+	return clients
+}
+
+// pace sends a tick for each payment to send, at the configured rate.
+func pace(ctx context.Context, config Config) <-chan struct{} {
+	ticks := make(chan struct{})
+	go func() {
+		defer close(ticks)
+		var deadline <-chan time.Time
+		if config.Duration > 0 {
+			timer := time.NewTimer(config.Duration)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+		start := time.Now()
+		for i := 0; config.Requests == 0 || i < config.Requests; i++ {
+			if config.Rate > 0 {
+				next := start.Add(time.Duration(float64(i) / config.Rate * float64(time.Second)))
+				select {
+				case <-time.After(time.Until(next)):
+				case <-deadline:
+					return
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case ticks <- struct{}{}:
+			case <-deadline:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ticks
+}
+
+func percentiles(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	// nearest rank
+	at := func(p float64) time.Duration {
+		return latencies[int(math.Ceil(p*float64(len(latencies))))-1]
+	}
+	return Latency{P50: at(0.5), P95: at(0.95), P99: at(0.99), Max: latencies[len(latencies)-1]}
+}
@@ -0,0 +1,180 @@
+package loadgen_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/form3tech-oss/interview-simulator/internal/loadgen"
+	"github.com/form3tech-oss/interview-simulator/simtest"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const rules = `
+rules:
+  - amounts: [1]
+    status: REJECTED
+    reason: Unlucky
+  - amounts: [2]
+    fault: drop
+  - amounts: [3]
+    delay: 20ms
+`
+
+type LoadgenTestSuite struct {
+	suite.Suite
+}
+
+func TestLoadgenSuite(t *testing.T) {
+	suite.Run(t, &LoadgenTestSuite{})
+}
+
+func (suite *LoadgenTestSuite) Test_ParseDistribution() {
+	tests := []struct {
+		spec     string
+		expected map[uint64]bool
+	}{
+		{spec: "10", expected: map[uint64]bool{10: true}},
+		{spec: "1-3", expected: map[uint64]bool{1: true, 2: true, 3: true}},
+		{spec: "10=1,20-21=3", expected: map[uint64]bool{10: true, 20: true, 21: true}},
+	}
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for _, tt := range tests {
+		suite.Run(tt.spec, func() {
+			d, err := loadgen.ParseDistribution(tt.spec)
+			suite.Require().NoError(err)
+
+			seen := make(map[uint64]bool)
+			for range 1000 {
+				seen[d.Amount(r)] = true
+			}
+			suite.Equal(tt.expected, seen)
+		})
+	}
+}
+
+func (suite *LoadgenTestSuite) Test_FullRangeDistribution() {
+	d, err := loadgen.ParseDistribution("0-18446744073709551615")
+	suite.Require().NoError(err)
+
+	r := rand.New(rand.NewPCG(1, 2))
+	suite.NotPanics(func() {
+		for range 100 {
+			d.Amount(r)
+		}
+	})
+}
+
+func (suite *LoadgenTestSuite) Test_ParseDistributionErrors() {
+	for _, spec := range []string{"", "abc", "10=0", "10=x", "5-1", "1-"} {
+		_, err := loadgen.ParseDistribution(spec)
+		suite.Error(err, spec)
+	}
+}
+
+func (suite *LoadgenTestSuite) Test_DistributionWeights() {
+	d, err := loadgen.ParseDistribution("1=9,2=1")
+	suite.Require().NoError(err)
+
+	r := rand.New(rand.NewPCG(1, 2))
+	ones := 0
+	for range 10000 {
+		if d.Amount(r) == 1 {
+			ones++
+		}
+	}
+	suite.InDelta(9000, ones, 300)
+}
+
+func (suite *LoadgenTestSuite) Test_RunReportsOutcomes() {
+	sim := simtest.Start(suite.T(), simtest.WithScenario(rules))
+	amounts, err := loadgen.ParseDistribution("1-4")
+	suite.Require().NoError(err)
+
+	report := loadgen.Run(context.Background(), loadgen.Config{
+		Target:      sim.Addr,
+		Connections: 4,
+		Requests:    200,
+		Amounts:     amounts,
+	})
+
+	suite.Equal(200, report.Requests)
+	suite.Equal(200, report.Statuses["ACCEPTED"]+report.Statuses["REJECTED"]+report.Dropped)
+	suite.Positive(report.Statuses["ACCEPTED"])
+	suite.Positive(report.Statuses["REJECTED"])
+	suite.Positive(report.Dropped)
+	suite.Zero(report.Errors)
+	suite.GreaterOrEqual(report.Latency.Max, 20*time.Millisecond)
+	suite.LessOrEqual(report.Latency.P50, report.Latency.P95)
+	suite.LessOrEqual(report.Latency.P95, report.Latency.P99)
+	suite.LessOrEqual(report.Latency.P99, report.Latency.Max)
+	suite.Len(sim.WaitForJournal(200), 200)
+}
+
+func (suite *LoadgenTestSuite) Test_RunAtRate() {
+	sim := simtest.Start(suite.T())
+	amounts, err := loadgen.ParseDistribution("10")
+	suite.Require().NoError(err)
+
+	report := loadgen.Run(context.Background(), loadgen.Config{
+		Target:      sim.Addr,
+		Connections: 2,
+		Rate:        100,
+		Duration:    500 * time.Millisecond,
+		Amounts:     amounts,
+	})
+
+	suite.InDelta(50, report.Requests, 5)
+	suite.InDelta(100, report.Throughput, 15)
+}
+
+func (suite *LoadgenTestSuite) Test_RunOpensAllConnections() {
+	sim := simtest.Start(suite.T())
+	amounts, err := loadgen.ParseDistribution("10")
+	suite.Require().NoError(err)
+
+	report := loadgen.Run(context.Background(), loadgen.Config{
+		Target:      sim.Addr,
+		Connections: 50,
+		Rate:        500,
+		Requests:    100,
+		Amounts:     amounts,
+	})
+
+	suite.Equal(100, report.Statuses["ACCEPTED"])
+	connections := make(map[uint64]bool)
+	for _, entry := range sim.WaitForJournal(100) {
+		connections[entry.ConnectionID] = true
+	}
+	suite.Len(connections, 50)
+}
+
+func (suite *LoadgenTestSuite) Test_WriteJSON() {
+	report := loadgen.Report{
+		Requests:   3,
+		Duration:   time.Second,
+		Throughput: 3,
+		Latency:    loadgen.Latency{P50: time.Millisecond, P95: 2 * time.Millisecond, P99: 2 * time.Millisecond, Max: 2500 * time.Microsecond},
+		Statuses:   map[string]int{"ACCEPTED": 2},
+		Dropped:    1,
+	}
+	var out bytes.Buffer
+
+	suite.Require().NoError(report.WriteJSON(&out))
+
+	var decoded map[string]any
+	suite.Require().NoError(json.Unmarshal(out.Bytes(), &decoded))
+	suite.Equal(map[string]any{
+		"requests":   3.0,
+		"durationMs": 1000.0,
+		"throughput": 3.0,
+		"latencyMs":  map[string]any{"p50": 1.0, "p95": 2.0, "p99": 2.0, "max": 2.5},
+		"statuses":   map[string]any{"ACCEPTED": 2.0},
+		"dropped":    1.0,
+		"errors":     0.0,
+	}, decoded)
+}
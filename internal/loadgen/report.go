@@ -0,0 +1,91 @@
+package loadgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Report summarises a load run. Latencies are of the payments that got a response.
+type Report struct {
+	Requests   int
+	Duration   time.Duration
+	Throughput float64
+	Latency    Latency
+	// Statuses counts the responses by status, e.g. ACCEPTED or REJECTED.
+	Statuses map[string]int
+	// Dropped counts the connections closed without a response.
+	Dropped int
+	// Errors counts the other failures, e.g. timeouts or malformed responses.
+	Errors int
+}
+
+type Latency struct {
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+type latencyJSON struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+type reportJSON struct {
+	Requests   int            `json:"requests"`
+	DurationMs float64        `json:"durationMs"`
+	Throughput float64        `json:"throughput"`
+	LatencyMs  latencyJSON    `json:"latencyMs"`
+	Statuses   map[string]int `json:"statuses"`
+	Dropped    int            `json:"dropped"`
+	Errors     int            `json:"errors"`
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (r Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(reportJSON{
+		Requests:   r.Requests,
+		DurationMs: milliseconds(r.Duration),
+		Throughput: r.Throughput,
+		LatencyMs: latencyJSON{
+			P50: milliseconds(r.Latency.P50),
+			P95: milliseconds(r.Latency.P95),
+			P99: milliseconds(r.Latency.P99),
+			Max: milliseconds(r.Latency.Max),
+		},
+		Statuses: r.Statuses,
+		Dropped:  r.Dropped,
+		Errors:   r.Errors,
+	})
+}
+
+func (r Report) WriteText(w io.Writer) error {
+	statuses := make([]string, 0, len(r.Statuses))
+	for status := range r.Statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	_, err := fmt.Fprintf(w, "requests:    %d in %s\n", r.Requests, r.Duration.Round(time.Millisecond))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "throughput:  %.1f/s\n", r.Throughput)
+	fmt.Fprintf(w, "latency:     p50 %s, p95 %s, p99 %s, max %s\n", r.Latency.P50, r.Latency.P95, r.Latency.P99, r.Latency.Max)
+	for _, status := range statuses {
+		fmt.Fprintf(w, "%-12s %d\n", status+":", r.Statuses[status])
+	}
+	fmt.Fprintf(w, "dropped:     %d\n", r.Dropped)
+	_, err = fmt.Fprintf(w, "errors:      %d\n", r.Errors)
+	return err
+}
@@ -0,0 +1,39 @@
+package metrics
+
+// Listener are the metrics of the listeners, labelled with the listener address.
+type Listener struct {
+	AcceptedConnections *Counter
+	// RefusedConnections counts the connections closed or rejected over the maximum.
+	RefusedConnections *Counter
+	ActiveConnections  *Gauge
+	// Requests counts the responses by status and reason, except the faulted ones.
+	Requests *Counter
+	// FaultedRequests counts the requests whose response was replaced with a fault, by
+	// fault.
+	FaultedRequests *Counter
+	// ProcessingDelay observes the seconds from receiving a request to sending its response.
+	ProcessingDelay *Histogram
+	// CancelledOnShutdown counts the requests cancelled when the grace period expired.
+	CancelledOnShutdown *Counter
+	// ThrottledRequests counts the requests rejected or delayed by the rate limits.
+	ThrottledRequests *Counter
+	// SequenceErrors counts the requests out of sequence, by error.
+	SequenceErrors *Counter
+	// OversizedRequests counts the requests longer than the maximum request size.
+	OversizedRequests *Counter
+}
+
+func NewListener(r *Registry) *Listener {
+	return &Listener{
+		AcceptedConnections: r.NewCounter("simulator_connections_accepted_total", "Connections accepted.", "listener"),
+		RefusedConnections:  r.NewCounter("simulator_connections_refused_total", "Connections closed or rejected over the maximum connections.", "listener"),
+		ActiveConnections:   r.NewGauge("simulator_connections_active", "Connections currently open.", "listener"),
+		Requests:            r.NewCounter("simulator_requests_total", "Requests responded, by status and reason.", "listener", "status", "reason"),
+		FaultedRequests:     r.NewCounter("simulator_requests_faulted_total", "Requests answered with an injected fault, by fault.", "listener", "fault"),
+		ProcessingDelay:     r.NewHistogram("simulator_processing_delay_seconds", "Time from receiving a request to sending its response.", DefaultBuckets, "listener"),
+		CancelledOnShutdown: r.NewCounter("simulator_requests_cancelled_on_shutdown_total", "Requests cancelled when the shutdown grace period expired.", "listener"),
+		ThrottledRequests:   r.NewCounter("simulator_requests_throttled_total", "Requests rejected or delayed by the rate limits.", "listener"),
+		SequenceErrors:      r.NewCounter("simulator_sequence_errors_total", "Requests out of sequence, by error: gap, duplicate or invalid.", "listener", "error"),
+		OversizedRequests:   r.NewCounter("simulator_requests_oversized_total", "Requests longer than the maximum request size.", "listener"),
+	}
+}
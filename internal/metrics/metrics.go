@@ -0,0 +1,290 @@
+// Package metrics implements counters, gauges and histograms exposed in the Prometheus
+// text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds of the histogram buckets, in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type collector interface {
+	write(w io.Writer) error
+}
+
+// Registry holds the metrics exposed together. It serves them over HTTP.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteText writes the metrics in the order they were created.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+	for _, c := range collectors {
+		if err := c.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	r.WriteText(w)
+}
+
+// metric keeps a value of type T for each combination of label values.
+type metric[T any] struct {
+	mu      sync.Mutex
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	samples map[string]*sample[T]
+	create  func() T
+}
+
+type sample[T any] struct {
+	labelValues []string
+	value       T
+}
+
+func newMetric[T any](name string, help string, kind string, labels []string, create func() T) *metric[T] {
+	return &metric[T]{name: name, help: help, kind: kind, labels: labels, samples: make(map[string]*sample[T]), create: create}
+}
+
+// update runs f on the value of the label values, with the metric locked.
+func (m *metric[T]) update(labelValues []string, f func(value *T)) {
+	if len(labelValues) != len(m.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", m.name, len(m.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.samples[key]
+	if !ok {
+		s = &sample[T]{labelValues: append([]string(nil), labelValues...), value: m.create()}
+		m.samples[key] = s
+	}
+	f(&s.value)
+}
+
+// value returns the value of the label values, the initial value if never updated.
+func (m *metric[T]) value(labelValues []string) T {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.samples[strings.Join(labelValues, "\xff")]; ok {
+		return s.value
+	}
+	return m.create()
+}
+
+// write writes the header and, sorted by label values, the lines of each sample.
+func (m *metric[T]) write(w io.Writer, lines func(labels string, value T) string) error {
+	m.mu.Lock()
+	keys := make([]string, 0, len(m.samples))
+	for key := range m.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n", m.name, escapeHelp(m.help))
+	fmt.Fprintf(&b, "# TYPE %s %s\n", m.name, m.kind)
+	for _, key := range keys {
+		s := m.samples[key]
+		b.WriteString(lines(formatLabels(m.labels, s.labelValues), s.value))
+	}
+	m.mu.Unlock()
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Counter is a value that only goes up, e.g. the number of requests.
+type Counter struct {
+	metric *metric[float64]
+}
+
+func (r *Registry) NewCounter(name string, help string, labels ...string) *Counter {
+	c := &Counter{metric: newMetric(name, help, "counter", labels, func() float64 { return 0 })}
+	r.register(c)
+	return c
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("counter %s can't decrease", c.metric.name))
+	}
+	c.metric.update(labelValues, func(value *float64) { *value += v })
+}
+
+// Value returns the value of the label values, 0 if never incremented.
+func (c *Counter) Value(labelValues ...string) float64 {
+	return c.metric.value(labelValues)
+}
+
+func (c *Counter) write(w io.Writer) error {
+	return c.metric.write(w, func(labels string, value float64) string {
+		return c.metric.name + labels + " " + formatValue(value) + "\n"
+	})
+}
+
+// Gauge is a value that goes up and down, e.g. the number of open connections.
+type Gauge struct {
+	metric *metric[float64]
+}
+
+func (r *Registry) NewGauge(name string, help string, labels ...string) *Gauge {
+	g := &Gauge{metric: newMetric(name, help, "gauge", labels, func() float64 { return 0 })}
+	r.register(g)
+	return g
+}
+
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.metric.update(labelValues, func(value *float64) { *value += v })
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.metric.update(labelValues, func(value *float64) { *value = v })
+}
+
+func (g *Gauge) Value(labelValues ...string) float64 {
+	return g.metric.value(labelValues)
+}
+
+func (g *Gauge) write(w io.Writer) error {
+	return g.metric.write(w, func(labels string, value float64) string {
+		return g.metric.name + labels + " " + formatValue(value) + "\n"
+	})
+}
+
+// Histogram counts observations, e.g. delays, in buckets of values.
+type Histogram struct {
+	metric  *metric[histogramValue]
+	buckets []float64
+}
+
+type histogramValue struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram creates a histogram with the upper bounds of the buckets in increasing order.
+// The +Inf bucket is added implicitly.
+func (r *Registry) NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{buckets: buckets}
+	h.metric = newMetric(name, help, "histogram", labels, func() histogramValue {
+		return histogramValue{counts: make([]uint64, len(buckets))}
+	})
+	r.register(h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.metric.update(labelValues, func(value *histogramValue) {
+		for i, bound := range h.buckets {
+			if v <= bound {
+				value.counts[i]++
+			}
+		}
+		value.count++
+		value.sum += v
+	})
+}
+
+// Count returns the number of observations of the label values.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	return h.metric.value(labelValues).count
+}
+
+func (h *Histogram) write(w io.Writer) error {
+	return h.metric.write(w, func(labels string, value histogramValue) string {
+		var b strings.Builder
+		for i, bound := range h.buckets {
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", h.metric.name, withLabel(labels, "le", formatValue(bound)), value.counts[i])
+		}
+		fmt.Fprintf(&b, "%s_bucket%s %d\n", h.metric.name, withLabel(labels, "le", "+Inf"), value.count)
+		fmt.Fprintf(&b, "%s_sum%s %s\n", h.metric.name, labels, formatValue(value.sum))
+		fmt.Fprintf(&b, "%s_count%s %d\n", h.metric.name, labels, value.count)
+		return b.String()
+	})
+}
+
+func formatLabels(names []string, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escapeLabel(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func withLabel(labels string, name string, value string) string {
+	pair := name + `="` + value + `"`
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
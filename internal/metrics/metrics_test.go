@@ -0,0 +1,99 @@
+package metrics_test
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/metrics"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+	registry *metrics.Registry
+}
+
+func TestMetricsSuite(t *testing.T) {
+	suite.Run(t, &MetricsTestSuite{})
+}
+
+func (suite *MetricsTestSuite) SetupTest() {
+	suite.registry = metrics.NewRegistry()
+}
+
+func (suite *MetricsTestSuite) text() string {
+	var b strings.Builder
+	suite.Require().NoError(suite.registry.WriteText(&b))
+	return b.String()
+}
+
+func (suite *MetricsTestSuite) Test_Counter() {
+	c := suite.registry.NewCounter("requests_total", "Requests.", "status", "reason")
+	c.Inc("REJECTED", "Invalid amount")
+	c.Inc("ACCEPTED", "Transaction processed")
+	c.Add(2, "ACCEPTED", "Transaction processed")
+
+	suite.Equal(3.0, c.Value("ACCEPTED", "Transaction processed"))
+	suite.Equal(0.0, c.Value("REJECTED", "Cancelled"))
+	suite.Equal(`# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{status="ACCEPTED",reason="Transaction processed"} 3
+requests_total{status="REJECTED",reason="Invalid amount"} 1
+`, suite.text())
+}
+
+func (suite *MetricsTestSuite) Test_CounterCannotDecrease() {
+	c := suite.registry.NewCounter("requests_total", "Requests.")
+
+	suite.Panics(func() { c.Add(-1) })
+}
+
+func (suite *MetricsTestSuite) Test_WrongNumberOfLabelValues() {
+	c := suite.registry.NewCounter("requests_total", "Requests.", "status")
+
+	suite.Panics(func() { c.Inc() })
+}
+
+func (suite *MetricsTestSuite) Test_Gauge() {
+	g := suite.registry.NewGauge("connections_active", "Open connections.")
+	g.Inc()
+	g.Inc()
+	g.Dec()
+
+	suite.Equal(1.0, g.Value())
+	suite.Equal(`# HELP connections_active Open connections.
+# TYPE connections_active gauge
+connections_active 1
+`, suite.text())
+
+	g.Set(0.5)
+	suite.Equal(0.5, g.Value())
+}
+
+func (suite *MetricsTestSuite) Test_Histogram() {
+	h := suite.registry.NewHistogram("delay_seconds", "Delays.", []float64{0.1, 1}, "listener")
+	h.Observe(0.05, ":8080")
+	h.Observe(0.1, ":8080")
+	h.Observe(0.5, ":8080")
+	h.Observe(2, ":8080")
+
+	suite.Equal(uint64(4), h.Count(":8080"))
+	suite.Equal(`# HELP delay_seconds Delays.
+# TYPE delay_seconds histogram
+delay_seconds_bucket{listener=":8080",le="0.1"} 2
+delay_seconds_bucket{listener=":8080",le="1"} 3
+delay_seconds_bucket{listener=":8080",le="+Inf"} 4
+delay_seconds_sum{listener=":8080"} 2.65
+delay_seconds_count{listener=":8080"} 4
+`, suite.text())
+}
+
+func (suite *MetricsTestSuite) Test_Escaping() {
+	c := suite.registry.NewCounter("requests_total", "Requests\nby \\reason.", "reason")
+	c.Inc("Said \"no\"\n")
+
+	suite.Equal(`# HELP requests_total Requests\nby \\reason.
+# TYPE requests_total counter
+requests_total{reason="Said \"no\"\n"} 1
+`, suite.text())
+}
@@ -0,0 +1,39 @@
+package mocks
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	tcp_listener "github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type MockSimulator struct {
+	mock.Mock
+}
+
+func (m *MockSimulator) Connections() []tcp_listener.ConnectionInfo {
+	args := m.Called()
+	return args.Get(0).([]tcp_listener.ConnectionInfo)
+}
+
+func (m *MockSimulator) Requests() []tcp_listener.RequestInfo {
+	args := m.Called()
+	return args.Get(0).([]tcp_listener.RequestInfo)
+}
+
+func (m *MockSimulator) Stop() {
+	m.Called()
+}
+
+func (m *MockSimulator) GracePeriod() time.Duration {
+	args := m.Called()
+	return args.Get(0).(time.Duration)
+}
+
+func (m *MockSimulator) SetGracePeriod(period time.Duration) {
+	m.Called(period)
+}
+
+func (m *MockSimulator) SetScenario(s *scenario.Scenario) {
+	m.Called(s)
+}
@@ -1,48 +1,58 @@
 package payment
 
 import (
+	"context"
+	"github.com/form3tech-oss/interview-simulator/internal/clock"
 	"github.com/form3tech-oss/interview-simulator/internal/response"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
 	"strconv"
 	"strings"
-	"time"
 )
 
 type Payment struct {
+	ID          string
 	Amount      uint64
+	Request     string
 	ErrorReason string
 }
 
+// FromString parses a PAYMENT|<amount> request, or PAYMENT|<id>|<amount> for payments
+// identified by the client.
 func FromString(request string) Payment {
 	parts := strings.Split(request, "|")
-	if len(parts) != 2 || parts[0] != "PAYMENT" {
-		return Payment{ErrorReason: "Invalid request"}
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "PAYMENT" {
+		return Payment{Request: request, ErrorReason: "Invalid request"}
 	}
 
-	amount, err := strconv.ParseUint(parts[1], 10, 64)
+	var id string
+	if len(parts) == 3 {
+		id = parts[1]
+		if id == "" {
+			return Payment{Request: request, ErrorReason: "Invalid request"}
+		}
+	}
+
+	amount, err := strconv.ParseUint(parts[len(parts)-1], 10, 64)
 	if err != nil {
-		return Payment{ErrorReason: "Invalid amount"}
+		return Payment{ID: id, Request: request, ErrorReason: "Invalid amount"}
 	}
-	return Payment{Amount: amount}
+	return Payment{ID: id, Amount: amount, Request: request}
 }
 
-func (p Payment) Process() response.Response {
+// Process applies the scenario to the payment, simulating the counterparty processing
+// delay. If ctx is cancelled before the processing completes, the payment is rejected as
+// cancelled.
+func (p Payment) Process(ctx context.Context, s *scenario.Scenario, clk clock.Clock) (response.Response, scenario.Fault) {
 	if p.ErrorReason != "" {
-		return response.NewRejected(p.ErrorReason)
+		return response.NewRejected(p.ErrorReason), scenario.FaultNone
 	}
 
-	processingTime := p.processingTime()
-	time.Sleep(processingTime * time.Millisecond)
-	return response.NewAccepted("Transaction processed")
-}
+	outcome := s.Evaluate(p.Amount, p.Request)
 
-func (p Payment) processingTime() (processingTime time.Duration) {
-	switch {
-	case p.Amount > 10000:
-		processingTime = 10000
-	case p.Amount > 100:
-		processingTime = time.Duration(p.Amount)
-	default:
-		processingTime = 0
+	select {
+	case <-clk.After(outcome.Delay):
+		return outcome.Response, outcome.Fault
+	case <-ctx.Done():
+		return response.NewRejected("Cancelled"), scenario.FaultNone
 	}
-	return
 }
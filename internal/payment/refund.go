@@ -0,0 +1,102 @@
+package payment
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/response"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	"strconv"
+	"strings"
+)
+
+// Refund returns part or all of an accepted payment, requested as
+// REFUND|<original-id>|<amount>.
+type Refund struct {
+	OriginalID  string
+	Amount      uint64
+	ErrorReason string
+}
+
+// Reversal cancels an accepted payment, requested as REVERSAL|<original-id>.
+type Reversal struct {
+	OriginalID  string
+	ErrorReason string
+}
+
+func RefundFromString(request string) Refund {
+	parts := strings.Split(request, "|")
+	if len(parts) != 3 || parts[0] != "REFUND" || parts[1] == "" {
+		return Refund{ErrorReason: "Invalid request"}
+	}
+	amount, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil || amount == 0 {
+		return Refund{OriginalID: parts[1], ErrorReason: "Invalid amount"}
+	}
+	return Refund{OriginalID: parts[1], Amount: amount}
+}
+
+func ReversalFromString(request string) Reversal {
+	parts := strings.Split(request, "|")
+	if len(parts) != 2 || parts[0] != "REVERSAL" || parts[1] == "" {
+		return Reversal{ErrorReason: "Invalid request"}
+	}
+	return Reversal{OriginalID: parts[1]}
+}
+
+// Refund refunds the original payment of the participant, up to the amount not refunded
+// yet.
+func (s *Store) Refund(participant string, r Refund) response.Response {
+	if r.ErrorReason != "" {
+		return response.NewRejected(r.ErrorReason)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	original, reason := s.accepted(participant, r.OriginalID)
+	switch {
+	case original == nil:
+		return response.NewRejected(reason)
+	case original.reversed:
+		return response.NewRejected("Payment reversed")
+	case r.Amount > original.amount-original.refunded:
+		return response.NewRejected("Refund exceeds payment")
+	}
+	original.refunded += r.Amount
+	return response.NewAccepted("Refund processed")
+}
+
+// Reverse reverses the original payment of the participant, unless it was refunded or
+// reversed already.
+func (s *Store) Reverse(participant string, r Reversal) response.Response {
+	if r.ErrorReason != "" {
+		return response.NewRejected(r.ErrorReason)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	original, reason := s.accepted(participant, r.OriginalID)
+	switch {
+	case original == nil:
+		return response.NewRejected(reason)
+	case original.reversed:
+		return response.NewRejected("Payment already reversed")
+	case original.refunded > 0:
+		return response.NewRejected("Payment refunded")
+	}
+	original.reversed = true
+	return response.NewAccepted("Reversal processed")
+}
+
+// accepted returns the completed and accepted payment of the participant with the id, or
+// the reason it can't be refunded or reversed. It must be called holding s.mu.
+func (s *Store) accepted(participant, id string) (*record, string) {
+	original, ok := s.payments[key{participant, id}]
+	if !ok {
+		return nil, "Unknown payment"
+	}
+	select {
+	case <-original.done:
+	default:
+		return nil, "Payment pending"
+	}
+	if original.response.Status != scenario.StatusAccepted {
+		return nil, "Payment not accepted"
+	}
+	return original, ""
+}
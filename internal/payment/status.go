@@ -0,0 +1,46 @@
+package payment
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/response"
+	"strings"
+)
+
+const (
+	StatusPending = "PENDING"
+	StatusUnknown = "UNKNOWN"
+)
+
+// Enquiry asks for the outcome of a payment, requested as STATUS|<payment-id>.
+type Enquiry struct {
+	PaymentID   string
+	ErrorReason string
+}
+
+func EnquiryFromString(request string) Enquiry {
+	parts := strings.Split(request, "|")
+	if len(parts) != 2 || parts[0] != "STATUS" || parts[1] == "" {
+		return Enquiry{ErrorReason: "Invalid request"}
+	}
+	return Enquiry{PaymentID: parts[1]}
+}
+
+// Status returns the response of the participant's payment, RESPONSE|PENDING|Payment
+// pending while it is processed, or RESPONSE|UNKNOWN|Unknown payment if the participant
+// sent no valid payment with the id.
+func (s *Store) Status(participant string, e Enquiry) response.Response {
+	if e.ErrorReason != "" {
+		return response.NewRejected(e.ErrorReason)
+	}
+	s.mu.Lock()
+	original, ok := s.payments[key{participant, e.PaymentID}]
+	s.mu.Unlock()
+	if !ok {
+		return response.Response{Status: StatusUnknown, Reason: "Unknown payment"}
+	}
+	select {
+	case <-original.done:
+		return original.response
+	default:
+		return response.Response{Status: StatusPending, Reason: "Payment pending"}
+	}
+}
@@ -0,0 +1,91 @@
+package payment
+
+import (
+	"context"
+	"github.com/form3tech-oss/interview-simulator/internal/clock"
+	"github.com/form3tech-oss/interview-simulator/internal/response"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	"strings"
+	"sync"
+)
+
+type record struct {
+	amount   uint64
+	done     chan struct{}
+	response response.Response
+	// refunded and reversed are guarded by the store mutex.
+	refunded uint64
+	reversed bool
+}
+
+// key identifies a payment by the participant that submitted it and its id, so
+// participants only see their own payments. The participant is empty without sessions.
+type key struct {
+	participant string
+	id          string
+}
+
+// Store processes the payments with the clock, remembering the ones submitted with an
+// id so resubmissions are not processed twice.
+type Store struct {
+	mu       sync.Mutex
+	payments map[key]*record
+	clock    clock.Clock
+}
+
+func NewStore(clk clock.Clock) *Store {
+	return &Store{payments: make(map[key]*record), clock: clk}
+}
+
+// Handle processes a PAYMENT, REFUND, REVERSAL or STATUS request from the participant,
+// which is empty when the listener runs without sessions.
+func (s *Store) Handle(ctx context.Context, participant, request string, rules *scenario.Scenario) (response.Response, scenario.Fault) {
+	kind, _, _ := strings.Cut(request, "|")
+	switch kind {
+	case "REFUND":
+		return s.Refund(participant, RefundFromString(request)), scenario.FaultNone
+	case "REVERSAL":
+		return s.Reverse(participant, ReversalFromString(request)), scenario.FaultNone
+	case "STATUS":
+		return s.Status(participant, EnquiryFromString(request)), scenario.FaultNone
+	}
+	return s.Process(ctx, participant, FromString(request), rules)
+}
+
+// Process processes the payment unless the participant already submitted its id. A
+// resubmission with the same amount gets the response of the original payment, once it's
+// completed, and one with a different amount is rejected as a duplicate.
+func (s *Store) Process(ctx context.Context, participant string, p Payment, rules *scenario.Scenario) (response.Response, scenario.Fault) {
+	if p.ID == "" || p.ErrorReason != "" {
+		return p.Process(ctx, rules, s.clock)
+	}
+
+	s.mu.Lock()
+	original, ok := s.payments[key{participant, p.ID}]
+	if !ok {
+		original = &record{amount: p.Amount, done: make(chan struct{})}
+		s.payments[key{participant, p.ID}] = original
+	}
+	s.mu.Unlock()
+
+	if ok {
+		return s.resubmitted(ctx, p, original), scenario.FaultNone
+	}
+
+	resp, fault := p.Process(ctx, rules, s.clock)
+	original.response = resp
+	close(original.done)
+	return resp, fault
+}
+
+func (s *Store) resubmitted(ctx context.Context, p Payment, original *record) response.Response {
+	if original.amount != p.Amount {
+		return response.NewRejected("Duplicate payment")
+	}
+	select {
+	case <-original.done:
+		return original.response
+	case <-ctx.Done():
+		return response.NewRejected("Cancelled")
+	}
+}
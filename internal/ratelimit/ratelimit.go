@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/clock"
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket allowing rate requests per second, and bursts of up to burst
+// requests. It starts full.
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	clock  clock.Clock
+}
+
+func NewBucket(rate float64, burst int, clk clock.Clock) *Bucket {
+	burst = max(burst, 1)
+	return &Bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: clk.Now(), clock: clk}
+}
+
+// Allow takes a token if there is one.
+func (b *Bucket) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// AllowAll takes a token from each of the buckets if they all have one, and none
+// otherwise. Nil buckets are skipped. Buckets shared by concurrent calls must be passed
+// in the same order.
+func AllowAll(buckets ...*Bucket) bool {
+	var locked []*Bucket
+	defer func() {
+		for _, b := range locked {
+			b.mu.Unlock()
+		}
+	}()
+	for _, b := range buckets {
+		if b == nil {
+			continue
+		}
+		b.mu.Lock()
+		locked = append(locked, b)
+		b.refill()
+		if b.tokens < 1 {
+			return false
+		}
+	}
+	for _, b := range locked {
+		b.tokens--
+	}
+	return true
+}
+
+// Reserve takes a token, returning how long to wait until it would have been available.
+func (b *Bucket) Reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (b *Bucket) refill() {
+	now := b.clock.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
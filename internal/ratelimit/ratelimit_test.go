@@ -0,0 +1,73 @@
+package ratelimit_test
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/clock"
+	"github.com/form3tech-oss/interview-simulator/internal/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimitTestSuite struct {
+	suite.Suite
+	clock *clock.Fake
+}
+
+func TestRateLimitSuite(t *testing.T) {
+	suite.Run(t, &RateLimitTestSuite{})
+}
+
+func (suite *RateLimitTestSuite) SetupTest() {
+	suite.clock = clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func (suite *RateLimitTestSuite) Test_AllowUpToTheBurst() {
+	bucket := ratelimit.NewBucket(2, 3, suite.clock)
+
+	for range 3 {
+		suite.True(bucket.Allow())
+	}
+	suite.False(bucket.Allow(), "Bucket should be empty after the burst")
+
+	suite.clock.Advance(400 * time.Millisecond)
+	suite.False(bucket.Allow(), "Token should not be refilled yet")
+	suite.clock.Advance(100 * time.Millisecond)
+	suite.True(bucket.Allow(), "Token should be refilled at the rate")
+	suite.False(bucket.Allow())
+}
+
+func (suite *RateLimitTestSuite) Test_RefillIsCappedAtTheBurst() {
+	bucket := ratelimit.NewBucket(10, 2, suite.clock)
+	suite.True(bucket.Allow())
+	suite.True(bucket.Allow())
+
+	suite.clock.Advance(time.Hour)
+
+	suite.True(bucket.Allow())
+	suite.True(bucket.Allow())
+	suite.False(bucket.Allow())
+}
+
+func (suite *RateLimitTestSuite) Test_AllowAll() {
+	first := ratelimit.NewBucket(1, 2, suite.clock)
+	second := ratelimit.NewBucket(1, 1, suite.clock)
+
+	suite.True(ratelimit.AllowAll(first, nil, second))
+	suite.False(ratelimit.AllowAll(first, second), "Second bucket should be empty")
+
+	suite.True(first.Allow(), "Tokens should not be taken unless all the buckets allow")
+	suite.False(first.Allow())
+}
+
+func (suite *RateLimitTestSuite) Test_Reserve() {
+	bucket := ratelimit.NewBucket(2, 1, suite.clock)
+
+	suite.Equal(time.Duration(0), bucket.Reserve())
+	suite.Equal(500*time.Millisecond, bucket.Reserve())
+	suite.Equal(time.Second, bucket.Reserve(), "Reservations should queue behind each other")
+	suite.False(bucket.Allow(), "Reserved tokens should not be available")
+
+	suite.clock.Advance(time.Second)
+	suite.Equal(500*time.Millisecond, bucket.Reserve())
+}
@@ -0,0 +1,306 @@
+package scenario
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/response"
+	"gopkg.in/yaml.v3"
+	"math"
+	"math/rand/v2"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	StatusAccepted = "ACCEPTED"
+	StatusRejected = "REJECTED"
+
+	defaultReason = "Transaction processed"
+)
+
+type Fault string
+
+const (
+	FaultNone Fault = ""
+	// FaultDrop closes the connection without sending a response.
+	FaultDrop Fault = "drop"
+	// FaultPartial sends the first half of the response and closes the connection.
+	FaultPartial Fault = "partial"
+	// FaultMalformed sends a line that doesn't follow the response format.
+	FaultMalformed Fault = "malformed"
+	// FaultDuplicate sends the response twice.
+	FaultDuplicate Fault = "duplicate"
+	// FaultNoNewline sends the response without the trailing newline.
+	FaultNoNewline Fault = "no-newline"
+	// FaultStall never responds, holding the request until the service shuts down.
+	FaultStall Fault = "stall"
+)
+
+// Scenario is an ordered list of rules deciding the outcome of a payment. The first
+// matching rule wins; payments not matching any rule are accepted without delay.
+// Fault profiles are evaluated afterwards and the first matching profile that passes
+// its probability check overrides the fault of the outcome.
+type Scenario struct {
+	Name   string         `yaml:"name"`
+	Rules  []Rule         `yaml:"rules"`
+	Faults []FaultProfile `yaml:"faults"`
+
+	// Random returns a number in [0.0, 1.0) for the fault probability checks. Defaults
+	// to rand.Float64.
+	Random func() float64 `yaml:"-"`
+}
+
+// Match holds the criteria of rules and fault profiles. It matches when all the
+// configured criteria match.
+type Match struct {
+	Amount  *Range   `yaml:"amount"`
+	Amounts []uint64 `yaml:"amounts"`
+	Request string   `yaml:"request"`
+
+	pattern *regexp.Regexp
+}
+
+type Rule struct {
+	Name  string `yaml:"name"`
+	Match `yaml:",inline"`
+
+	Status   string        `yaml:"status"`
+	Reason   string        `yaml:"reason"`
+	Delay    Delay         `yaml:"delay"`
+	MaxDelay time.Duration `yaml:"maxDelay"`
+	Fault    Fault         `yaml:"fault"`
+}
+
+type FaultProfile struct {
+	Name  string `yaml:"name"`
+	Match `yaml:",inline"`
+
+	Fault Fault `yaml:"fault"`
+	// Probability of injecting the fault in matching payments. Defaults to 1.
+	Probability *float64 `yaml:"probability"`
+}
+
+type Range struct {
+	Min uint64  `yaml:"min"`
+	Max *uint64 `yaml:"max"`
+}
+
+// Delay is either a fixed duration, such as "250ms", or "amount" to delay the response
+// by as many milliseconds as the payment amount.
+type Delay struct {
+	Fixed      time.Duration
+	FromAmount bool
+}
+
+type Outcome struct {
+	Response response.Response
+	Delay    time.Duration
+	Fault    Fault
+}
+
+const (
+	DefaultDelayThreshold = 100
+	DefaultMaxDelay       = 10 * time.Second
+)
+
+// Default reproduces the scheme behaviour: amounts greater than 100 are delayed by the
+// amount in milliseconds, up to 10 seconds.
+func Default() *Scenario {
+	return NewDefault(DefaultDelayThreshold, DefaultMaxDelay)
+}
+
+// NewDefault is the default scenario delaying the amounts greater than the threshold, up
+// to maxDelay. A zero maxDelay doesn't cap the delay.
+func NewDefault(threshold uint64, maxDelay time.Duration) *Scenario {
+	s := &Scenario{Name: "default"}
+	// no amount is greater than the maximum threshold
+	if threshold < math.MaxUint64 {
+		s.Rules = []Rule{
+			{
+				Name:     "counterparty processing delay",
+				Match:    Match{Amount: &Range{Min: threshold + 1}},
+				Delay:    Delay{FromAmount: true},
+				MaxDelay: maxDelay,
+			},
+		}
+	}
+	return s
+}
+
+// Load reads a scenario from a YAML or JSON file.
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Scenario, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var s Scenario
+	if err := decoder.Decode(&s); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	return &s, nil
+}
+
+func (s *Scenario) compile() error {
+	for i := range s.Rules {
+		rule := &s.Rules[i]
+		if err := rule.compile(); err != nil {
+			return fmt.Errorf("rule %d (%s): %w", i, rule.Name, err)
+		}
+	}
+	for i := range s.Faults {
+		profile := &s.Faults[i]
+		if err := profile.compile(); err != nil {
+			return fmt.Errorf("fault %d (%s): %w", i, profile.Name, err)
+		}
+	}
+	return nil
+}
+
+func (p *FaultProfile) compile() error {
+	if p.Fault == FaultNone {
+		return errors.New("fault is required")
+	}
+	if err := p.Fault.validate(); err != nil {
+		return err
+	}
+	if p.Probability != nil && (*p.Probability < 0 || *p.Probability > 1) {
+		return fmt.Errorf("probability %v is not between 0 and 1", *p.Probability)
+	}
+	return p.Match.compile()
+}
+
+func (f Fault) validate() error {
+	switch f {
+	case FaultNone, FaultDrop, FaultPartial, FaultMalformed, FaultDuplicate, FaultNoNewline, FaultStall:
+		return nil
+	default:
+		return fmt.Errorf("unknown fault %q", f)
+	}
+}
+
+func (r *Rule) compile() error {
+	switch r.Status {
+	case "", StatusAccepted:
+	case StatusRejected:
+		if r.Reason == "" {
+			return errors.New("rejected rules require a reason")
+		}
+	default:
+		return fmt.Errorf("unknown status %q", r.Status)
+	}
+	if err := r.Fault.validate(); err != nil {
+		return err
+	}
+	return r.Match.compile()
+}
+
+func (m *Match) compile() error {
+	if m.Amount != nil && m.Amount.Max != nil && *m.Amount.Max < m.Amount.Min {
+		return errors.New("amount max is lower than min")
+	}
+	if m.Request != "" {
+		pattern, err := regexp.Compile(m.Request)
+		if err != nil {
+			return err
+		}
+		m.pattern = pattern
+	}
+	return nil
+}
+
+// Evaluate returns the outcome of the first rule matching the payment, with the fault of
+// the first fault profile triggered by it.
+func (s *Scenario) Evaluate(amount uint64, request string) Outcome {
+	outcome := Outcome{Response: response.NewAccepted(defaultReason)}
+	for _, rule := range s.Rules {
+		if rule.matches(amount, request) {
+			outcome = rule.outcome(amount)
+			break
+		}
+	}
+	for _, profile := range s.Faults {
+		if profile.matches(amount, request) && s.roll(profile.Probability) {
+			outcome.Fault = profile.Fault
+			break
+		}
+	}
+	return outcome
+}
+
+func (s *Scenario) roll(probability *float64) bool {
+	if probability == nil {
+		return true
+	}
+	random := rand.Float64
+	if s.Random != nil {
+		random = s.Random
+	}
+	return random() < *probability
+}
+
+func (m *Match) matches(amount uint64, request string) bool {
+	if m.Amount != nil && (amount < m.Amount.Min || (m.Amount.Max != nil && amount > *m.Amount.Max)) {
+		return false
+	}
+	if len(m.Amounts) > 0 && !slices.Contains(m.Amounts, amount) {
+		return false
+	}
+	if m.pattern != nil && !m.pattern.MatchString(request) {
+		return false
+	}
+	return true
+}
+
+func (r *Rule) outcome(amount uint64) Outcome {
+	resp := response.NewAccepted(defaultReason)
+	if r.Reason != "" {
+		resp.Reason = r.Reason
+	}
+	if r.Status == StatusRejected {
+		resp = response.NewRejected(r.Reason)
+	}
+
+	delay := r.Delay.Fixed
+	if r.Delay.FromAmount {
+		// saturates instead of overflowing, before the maximum delay applies
+		delay = time.Duration(math.MaxInt64)
+		if amount <= uint64(math.MaxInt64/time.Millisecond) {
+			delay = time.Duration(amount) * time.Millisecond
+		}
+	}
+	if r.MaxDelay > 0 && delay > r.MaxDelay {
+		delay = r.MaxDelay
+	}
+	return Outcome{Response: resp, Delay: delay, Fault: r.Fault}
+}
+
+func (d *Delay) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	if strings.TrimSpace(s) == "amount" {
+		*d = Delay{FromAmount: true}
+		return nil
+	}
+	fixed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid delay %q: %w", s, err)
+	}
+	*d = Delay{Fixed: fixed}
+	return nil
+}
@@ -0,0 +1,195 @@
+package scenario_test
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/response"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const rules = `
+name: test
+rules:
+  - name: insufficient funds
+    amount: {min: 5000, max: 5999}
+    status: REJECTED
+    reason: Insufficient funds
+  - name: lost payment
+    amounts: [1234, 4321]
+    fault: drop
+  - name: nines
+    request: '^PAYMENT\|9+$'
+    reason: Nines processed
+    delay: 250ms
+  - name: slow counterparty
+    amount: {min: 101}
+    delay: amount
+    maxDelay: 2s
+`
+
+type ScenarioTestSuite struct {
+	suite.Suite
+}
+
+func TestScenarioSuite(t *testing.T) {
+	suite.Run(t, &ScenarioTestSuite{})
+}
+
+func (suite *ScenarioTestSuite) Test_Evaluate() {
+	s, err := scenario.Parse([]byte(rules))
+	suite.Require().NoError(err)
+
+	tests := []struct {
+		name     string
+		amount   uint64
+		request  string
+		expected scenario.Outcome
+	}{
+		{
+			name:     "No matching rule",
+			amount:   10,
+			request:  "PAYMENT|10",
+			expected: scenario.Outcome{Response: response.NewAccepted("Transaction processed")},
+		},
+		{
+			name:     "Amount range lower bound",
+			amount:   5000,
+			request:  "PAYMENT|5000",
+			expected: scenario.Outcome{Response: response.NewRejected("Insufficient funds")},
+		},
+		{
+			name:     "Amount range upper bound",
+			amount:   5999,
+			request:  "PAYMENT|5999",
+			expected: scenario.Outcome{Response: response.NewRejected("Insufficient funds")},
+		},
+		{
+			name:     "Exact amount",
+			amount:   4321,
+			request:  "PAYMENT|4321",
+			expected: scenario.Outcome{Response: response.NewAccepted("Transaction processed"), Fault: scenario.FaultDrop},
+		},
+		{
+			name:     "Request regex",
+			amount:   999,
+			request:  "PAYMENT|999",
+			expected: scenario.Outcome{Response: response.NewAccepted("Nines processed"), Delay: 250 * time.Millisecond},
+		},
+		{
+			name:     "Delay from amount",
+			amount:   1500,
+			request:  "PAYMENT|1500",
+			expected: scenario.Outcome{Response: response.NewAccepted("Transaction processed"), Delay: 1500 * time.Millisecond},
+		},
+		{
+			name:     "Delay capped",
+			amount:   6000,
+			request:  "PAYMENT|6000",
+			expected: scenario.Outcome{Response: response.NewAccepted("Transaction processed"), Delay: 2 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			suite.Equal(tt.expected, s.Evaluate(tt.amount, tt.request))
+		})
+	}
+}
+
+func (suite *ScenarioTestSuite) Test_FaultProfiles() {
+	s, err := scenario.Parse([]byte(`
+rules:
+  - amounts: [42]
+    status: REJECTED
+    reason: Unlucky
+faults:
+  - name: flaky network
+    amount: {min: 1000}
+    fault: partial
+    probability: 0.25
+  - name: duplicates
+    request: '\|42$'
+    fault: duplicate
+`))
+	suite.Require().NoError(err)
+
+	random := 0.0
+	s.Random = func() float64 { return random }
+
+	random = 0.2
+	suite.Equal(scenario.FaultPartial, s.Evaluate(1000, "PAYMENT|1000").Fault)
+	random = 0.3
+	suite.Equal(scenario.FaultNone, s.Evaluate(1000, "PAYMENT|1000").Fault)
+	suite.Equal(scenario.FaultNone, s.Evaluate(999, "PAYMENT|999").Fault)
+
+	outcome := s.Evaluate(42, "PAYMENT|42")
+	suite.Equal(scenario.FaultDuplicate, outcome.Fault)
+	suite.Equal(response.NewRejected("Unlucky"), outcome.Response)
+}
+
+func (suite *ScenarioTestSuite) Test_Default() {
+	s := scenario.Default()
+
+	suite.Equal(time.Duration(0), s.Evaluate(100, "PAYMENT|100").Delay)
+	suite.Equal(101*time.Millisecond, s.Evaluate(101, "PAYMENT|101").Delay)
+	suite.Equal(10*time.Second, s.Evaluate(20000, "PAYMENT|20000").Delay)
+	suite.Equal(response.NewAccepted("Transaction processed"), s.Evaluate(20000, "PAYMENT|20000").Response)
+	suite.Equal(10*time.Second, s.Evaluate(9223372036854776, "PAYMENT|9223372036854776").Delay)
+	suite.Equal(10*time.Second, s.Evaluate(math.MaxUint64, "PAYMENT|18446744073709551615").Delay)
+}
+
+func (suite *ScenarioTestSuite) Test_NewDefault() {
+	s := scenario.NewDefault(1000, 2*time.Second)
+
+	suite.Equal(time.Duration(0), s.Evaluate(1000, "PAYMENT|1000").Delay)
+	suite.Equal(1001*time.Millisecond, s.Evaluate(1001, "PAYMENT|1001").Delay)
+	suite.Equal(2*time.Second, s.Evaluate(20000, "PAYMENT|20000").Delay)
+}
+
+func (suite *ScenarioTestSuite) Test_NewDefaultWithMaximumThreshold() {
+	s := scenario.NewDefault(math.MaxUint64, 0)
+
+	suite.Equal(time.Duration(0), s.Evaluate(5, "PAYMENT|5").Delay)
+	suite.Equal(time.Duration(0), s.Evaluate(math.MaxUint64, "PAYMENT|18446744073709551615").Delay)
+}
+
+func (suite *ScenarioTestSuite) Test_LoadJSON() {
+	path := filepath.Join(suite.T().TempDir(), "scenario.json")
+	err := os.WriteFile(path, []byte(`{"name": "json", "rules": [{"amounts": [7], "status": "REJECTED", "reason": "Unlucky"}]}`), 0o600)
+	suite.Require().NoError(err)
+
+	s, err := scenario.Load(path)
+
+	suite.Require().NoError(err)
+	suite.Equal("json", s.Name)
+	suite.Equal(response.NewRejected("Unlucky"), s.Evaluate(7, "PAYMENT|7").Response)
+}
+
+func (suite *ScenarioTestSuite) Test_InvalidScenarios() {
+	tests := []struct {
+		name  string
+		rules string
+	}{
+		{name: "Unknown field", rules: `rules: [{amout: {min: 1}}]`},
+		{name: "Unknown status", rules: `rules: [{status: PENDING}]`},
+		{name: "Rejected without reason", rules: `rules: [{status: REJECTED}]`},
+		{name: "Unknown fault", rules: `rules: [{fault: explode}]`},
+		{name: "Fault profile without fault", rules: `faults: [{amounts: [1]}]`},
+		{name: "Fault profile with invalid probability", rules: `faults: [{fault: drop, probability: 1.5}]`},
+		{name: "Invalid range", rules: `rules: [{amount: {min: 10, max: 5}}]`},
+		{name: "Invalid regex", rules: `rules: [{request: "("}]`},
+		{name: "Invalid delay", rules: `rules: [{delay: soon}]`},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			_, err := scenario.Parse([]byte(tt.rules))
+			suite.Error(err)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package session
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LogonRequest starts a session with LOGON|<participant-id>|<secret>.
+const LogonRequest = "LOGON"
+
+// redactedSecret replaces the secrets of the logon requests stored in the journal.
+const redactedSecret = "***"
+
+// Participant is allowed to log on with its id and secret.
+type Participant struct {
+	ID     string `yaml:"id"`
+	Secret string `yaml:"secret"`
+	// ScenarioFile is applied to the payments of the participant instead of the scenario
+	// of the listener. Optional, relative to the participants file.
+	ScenarioFile string `yaml:"scenario"`
+	// RateLimit is the number of requests per second allowed on all the connections of
+	// the participant, on top of the rate limits of the listener. Optional.
+	RateLimit float64 `yaml:"rateLimit"`
+
+	Scenario *scenario.Scenario `yaml:"-"`
+}
+
+// Participants is the list of participants allowed to log on.
+type Participants struct {
+	byID map[string]*Participant
+}
+
+// Load reads the participants from a YAML or JSON file, e.g.
+//
+//	participants:
+//	  - id: bank-a
+//	    secret: s3cret
+//	    scenario: bank-a.yaml
+//	    rateLimit: 100
+func Load(path string) (*Participants, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var file struct {
+		Participants []*Participant `yaml:"participants"`
+	}
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid participants: %w", err)
+	}
+
+	p := &Participants{byID: make(map[string]*Participant, len(file.Participants))}
+	for i, participant := range file.Participants {
+		if err := p.add(participant, filepath.Dir(path)); err != nil {
+			return nil, fmt.Errorf("invalid participant %d (%s): %w", i, participant.ID, err)
+		}
+	}
+	return p, nil
+}
+
+func (p *Participants) add(participant *Participant, dir string) error {
+	switch {
+	case participant.ID == "":
+		return errors.New("missing id")
+	case participant.Secret == "":
+		return errors.New("missing secret")
+	case p.byID[participant.ID] != nil:
+		return errors.New("duplicate id")
+	case participant.RateLimit < 0:
+		return errors.New("rate limit can't be negative")
+	}
+	if participant.ScenarioFile != "" {
+		path := participant.ScenarioFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		rules, err := scenario.Load(path)
+		if err != nil {
+			return err
+		}
+		participant.Scenario = rules
+	}
+	p.byID[participant.ID] = participant
+	return nil
+}
+
+// Redact hides the secret of a logon request, so it isn't stored in the journal.
+func Redact(request string) string {
+	if parts := strings.Split(request, "|"); len(parts) == 3 && parts[0] == LogonRequest {
+		return parts[0] + "|" + parts[1] + "|" + redactedSecret
+	}
+	return request
+}
+
+// Unredact puts back the secret of the participant in a logon request hidden by Redact,
+// so it can be replayed. Other requests are returned unchanged.
+func (p *Participants) Unredact(request string) string {
+	parts := strings.Split(request, "|")
+	if len(parts) != 3 || parts[0] != LogonRequest || parts[2] != redactedSecret {
+		return request
+	}
+	participant, ok := p.byID[parts[1]]
+	if !ok {
+		return request
+	}
+	return parts[0] + "|" + parts[1] + "|" + participant.Secret
+}
+
+// Authenticate returns the participant with the id, if the secret matches.
+func (p *Participants) Authenticate(id string, secret string) (*Participant, bool) {
+	participant, ok := p.byID[id]
+	if !ok || subtle.ConstantTimeCompare([]byte(participant.Secret), []byte(secret)) != 1 {
+		return nil, false
+	}
+	return participant, true
+}
@@ -0,0 +1,87 @@
+package session_test
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/session"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SessionTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestSessionSuite(t *testing.T) {
+	suite.Run(t, &SessionTestSuite{})
+}
+
+func (suite *SessionTestSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+}
+
+func (suite *SessionTestSuite) writeFile(name string, content string) string {
+	path := filepath.Join(suite.dir, name)
+	suite.Require().NoError(os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func (suite *SessionTestSuite) Test_Authenticate() {
+	suite.writeFile("bank-b.yaml", "rules: [{amounts: [13], status: REJECTED, reason: Unlucky}]")
+	path := suite.writeFile("participants.yaml", `
+participants:
+  - id: bank-a
+    secret: s3cret
+  - id: bank-b
+    secret: other
+    scenario: bank-b.yaml
+`)
+	participants, err := session.Load(path)
+	suite.Require().NoError(err)
+
+	participant, ok := participants.Authenticate("bank-a", "s3cret")
+	suite.True(ok)
+	suite.Equal("bank-a", participant.ID)
+	suite.Nil(participant.Scenario)
+
+	participant, ok = participants.Authenticate("bank-b", "other")
+	suite.True(ok)
+	suite.Require().NotNil(participant.Scenario, "Scenario should be loaded relative to the participants file")
+	suite.Equal("Unlucky", participant.Scenario.Evaluate(13, "PAYMENT|13").Response.Reason)
+
+	_, ok = participants.Authenticate("bank-a", "other")
+	suite.False(ok, "Wrong secret")
+	_, ok = participants.Authenticate("bank-c", "s3cret")
+	suite.False(ok, "Unknown participant")
+}
+
+func (suite *SessionTestSuite) Test_RedactAndUnredact() {
+	participants, err := session.Load(suite.writeFile("participants.yaml", "participants: [{id: bank-a, secret: s3cret}]"))
+	suite.Require().NoError(err)
+
+	redacted := session.Redact("LOGON|bank-a|s3cret")
+	suite.Equal("LOGON|bank-a|***", redacted)
+	suite.Equal("LOGON|bank-a|s3cret", participants.Unredact(redacted))
+
+	for _, request := range []string{"PAYMENT|10", "LOGON|bank-a", "LOGON|bank-c|***"} {
+		suite.Equal(request, session.Redact(request))
+		suite.Equal(request, participants.Unredact(request))
+	}
+}
+
+func (suite *SessionTestSuite) Test_InvalidParticipants() {
+	for content, expected := range map[string]string{
+		"participants: [{secret: s3cret}]":                                 "invalid participant 0 (): missing id",
+		"participants: [{id: bank-a}]":                                     "invalid participant 0 (bank-a): missing secret",
+		"participants: [{id: bank-a, secret: a}, {id: bank-a, secret: b}]": "invalid participant 1 (bank-a): duplicate id",
+		"participants: [{id: bank-a, secret: a, scenario: missing.yaml}]":  "missing.yaml",
+		"participants: [{id: bank-a, secret: a, rateLimit: -1}]":           "invalid participant 0 (bank-a): rate limit can't be negative",
+		"participants: [{id: bank-a, password: a}]":                        "field password not found",
+	} {
+		_, err := session.Load(suite.writeFile("participants.yaml", content))
+
+		suite.ErrorContains(err, expected, content)
+	}
+}
@@ -0,0 +1,76 @@
+package tcp_listener
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/ratelimit"
+	"github.com/form3tech-oss/interview-simulator/internal/session"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// connection is an accepted client connection.
+type connection struct {
+	net.Conn
+	id         uint64
+	acceptedAt time.Time
+	writeMu    sync.Mutex
+	// clientSubject is the subject of the client certificate in TLS connections.
+	clientSubject string
+
+	// limiter is the rate limit of the connection, nil if disabled.
+	limiter *ratelimit.Bucket
+
+	// received is set when a line is received, and awaitingAck while a heartbeat is
+	// unacknowledged.
+	received    atomic.Bool
+	awaitingAck atomic.Bool
+
+	// participant is logged on the connection, nil before logon.
+	participant atomic.Pointer[session.Participant]
+
+	// receivedSeq is the sequence number of the last request in sequence, and sentSeq
+	// of the last line sent, guarded by writeMu.
+	receivedSeq uint64
+	sentSeq     uint64
+
+	// inFlight counts the requests of the connection in the request registry.
+	inFlight atomic.Int64
+
+	// pipelined connections process their requests concurrently, pending tracks them.
+	pipelined bool
+	pending   sync.WaitGroup
+}
+
+func (c *connection) participantID() string {
+	if participant := c.participant.Load(); participant != nil {
+		return participant.ID
+	}
+	return ""
+}
+
+// isActive reports whether the connection has a request in flight.
+func (c *connection) isActive() bool {
+	return c.inFlight.Load() > 0
+}
+
+type ConnectionInfo struct {
+	ID         uint64    `json:"id"`
+	Listener   string    `json:"listener"`
+	RemoteAddr string    `json:"remoteAddr"`
+	AcceptedAt time.Time `json:"acceptedAt"`
+	Active     bool      `json:"active"`
+	Pipelined  bool      `json:"pipelined"`
+	// ClientSubject is the subject of the client certificate in mutual TLS connections.
+	ClientSubject string `json:"clientSubject,omitempty"`
+	// Participant is logged on the connection, when sessions are enabled.
+	Participant string `json:"participant,omitempty"`
+}
+
+type RequestInfo struct {
+	ID           uint64    `json:"id"`
+	Listener     string    `json:"listener"`
+	ConnectionID uint64    `json:"connectionId"`
+	Request      string    `json:"request"`
+	StartedAt    time.Time `json:"startedAt"`
+}
@@ -0,0 +1,87 @@
+package tcp_listener
+
+import (
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/response"
+	"io"
+	"net"
+	"time"
+)
+
+// ConnectionPolicy decides what happens to the connections over the maximum.
+type ConnectionPolicy string
+
+const (
+	// ConnectionsRefuse closes the connections as soon as they are accepted.
+	ConnectionsRefuse ConnectionPolicy = "refuse"
+	// ConnectionsQueue stops accepting connections, leaving them in the accept queue until
+	// an open connection closes.
+	ConnectionsQueue ConnectionPolicy = "queue"
+	// ConnectionsReject responds RESPONSE|REJECTED|Too many connections and closes them.
+	ConnectionsReject ConnectionPolicy = "reject"
+)
+
+// rejectTimeout limits the time spent sending the rejection to a connection over the maximum.
+const rejectTimeout = time.Second
+
+func (p ConnectionPolicy) validate() error {
+	switch p {
+	case ConnectionsRefuse, ConnectionsQueue, ConnectionsReject:
+		return nil
+	}
+	return fmt.Errorf("unknown connection limit policy %q", p)
+}
+
+// waitForSlot blocks while the queue policy holds the connections in the accept queue. It
+// returns false if the listener stopped.
+func (l *TcpListener) waitForSlot() bool {
+	if l.slots == nil || l.options.ConnectionLimit != ConnectionsQueue {
+		return true
+	}
+	// only the accept loop takes slots, so the slot stays free until acquireSlot
+	select {
+	case l.slots <- struct{}{}:
+		<-l.slots
+		return true
+	case <-l.stopping:
+		return false
+	}
+}
+
+// acquireSlot takes a slot for an accepted connection, released by releaseSlot once the
+// connection is handled. It returns false if the maximum connections are open.
+func (l *TcpListener) acquireSlot() bool {
+	if l.slots == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *TcpListener) releaseSlot() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// refuseConnection applies the connection limit policy to a connection over the maximum.
+func (l *TcpListener) refuseConnection(conn net.Conn) {
+	defer conn.Close()
+	l.deps.Metrics.RefusedConnections.Inc(l.address)
+	l.deps.Logger.Info().Str("remoteAddr", conn.RemoteAddr().String()).Str("policy", string(l.options.ConnectionLimit)).Msg("Too many connections, refusing connection.")
+	if l.options.ConnectionLimit != ConnectionsReject {
+		return
+	}
+	rejected := response.NewRejected("Too many connections")
+	err := conn.SetWriteDeadline(time.Now().Add(rejectTimeout))
+	if err == nil {
+		_, err = io.WriteString(conn, rejected.ToString()+"\n")
+	}
+	if err != nil {
+		l.deps.Logger.Debug().Err(err).Msg("Error rejecting connection.")
+	}
+}
@@ -0,0 +1,125 @@
+package tcp_listener_test
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/metrics"
+	"github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"github.com/rs/zerolog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ConnectionLimitTestSuite struct {
+	suite.Suite
+	address string
+	metrics *metrics.Listener
+}
+
+func TestConnectionLimitSuite(t *testing.T) {
+	suite.Run(t, &ConnectionLimitTestSuite{})
+}
+
+func (suite *ConnectionLimitTestSuite) start(maxConnections int, policy tcp_listener.ConnectionPolicy) {
+	suite.metrics = metrics.NewListener(metrics.NewRegistry())
+	listener := startListener(suite.T(), tcp_listener.Options{MaxConnections: maxConnections, ConnectionLimit: policy}, tcp_listener.TcpListenerDeps{Metrics: suite.metrics})
+	suite.address = listener.Addr().String()
+}
+
+func (suite *ConnectionLimitTestSuite) dial() *testConn {
+	return dial(suite.T(), suite.address)
+}
+
+// pay sends a payment and returns the response, or the error reading it.
+func (suite *ConnectionLimitTestSuite) pay(conn *testConn) (string, error) {
+	conn.write("PAYMENT|10")
+	return conn.read()
+}
+
+func (suite *ConnectionLimitTestSuite) Test_ConnectionsOverTheMaximumAreRefused() {
+	suite.start(1, tcp_listener.ConnectionsRefuse)
+	first := suite.dial()
+	response, err := suite.pay(first)
+	suite.Require().NoError(err)
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", response)
+
+	_, err = suite.dial().read()
+	suite.Error(err, "Connection should be closed")
+	suite.Equal(1.0, suite.metrics.RefusedConnections.Value(suite.address))
+
+	first.Close()
+	suite.Eventually(func() bool {
+		response, err := suite.pay(suite.dial())
+		return err == nil && response == "RESPONSE|ACCEPTED|Transaction processed"
+	}, time.Second, 10*time.Millisecond, "Connection should be accepted once the first one closes")
+}
+
+func (suite *ConnectionLimitTestSuite) Test_ConnectionsOverTheMaximumAreRejected() {
+	suite.start(1, tcp_listener.ConnectionsReject)
+	first := suite.dial()
+	_, err := suite.pay(first)
+	suite.Require().NoError(err)
+
+	conn := suite.dial()
+	response, err := conn.read()
+	suite.NoError(err, "Failed to read response")
+	suite.Equal("RESPONSE|REJECTED|Too many connections", response)
+	_, err = conn.read()
+	suite.Error(err, "Connection should be closed")
+}
+
+func (suite *ConnectionLimitTestSuite) Test_ConnectionsOverTheMaximumAreQueued() {
+	suite.start(1, tcp_listener.ConnectionsQueue)
+	first := suite.dial()
+	_, err := suite.pay(first)
+	suite.Require().NoError(err)
+
+	queued := suite.dial()
+	responses := make(chan string, 1)
+	go func() {
+		response, _ := suite.pay(queued)
+		responses <- response
+	}()
+	select {
+	case <-responses:
+		suite.Fail("Queued connection should not be handled while the first one is open")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	first.Close()
+	select {
+	case response := <-responses:
+		suite.Equal("RESPONSE|ACCEPTED|Transaction processed", response)
+	case <-time.After(time.Second):
+		suite.Fail("Queued connection was not handled once the first one closed")
+	}
+	suite.Equal(0.0, suite.metrics.RefusedConnections.Value(suite.address))
+}
+
+func (suite *ConnectionLimitTestSuite) Test_StopWhileQueueing() {
+	listener, err := tcp_listener.New("localhost:0", 0, tcp_listener.Options{MaxConnections: 1, ConnectionLimit: tcp_listener.ConnectionsQueue}, &tcp_listener.TcpListenerDeps{Logger: zerolog.Nop(), Listener: tcp_listener.NetListener{}, NewScanner: tcp_listener.BufioScanner{}})
+	suite.Require().NoError(err)
+	stopped := make(chan struct{})
+	go func() {
+		listener.Start()
+		close(stopped)
+	}()
+	suite.address = listener.Addr().String()
+	first := suite.dial()
+	_, err = suite.pay(first)
+	suite.Require().NoError(err)
+
+	listener.Stop()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		suite.Fail("Listener did not stop")
+	}
+}
+
+func (suite *ConnectionLimitTestSuite) Test_UnknownPolicy() {
+	_, err := tcp_listener.New("localhost:0", 0, tcp_listener.Options{ConnectionLimit: "drop"}, &tcp_listener.TcpListenerDeps{Logger: zerolog.Nop()})
+
+	suite.ErrorContains(err, `unknown connection limit policy "drop"`)
+}
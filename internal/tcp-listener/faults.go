@@ -0,0 +1,63 @@
+package tcp_listener
+
+import (
+	"errors"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// errConnectionDropped is returned by injectFault when the fault requires the connection
+// to be closed.
+var errConnectionDropped = errors.New("connection dropped by fault")
+
+func (l *TcpListener) injectFault(connection *connection, fault scenario.Fault, resp string) error {
+	l.deps.Logger.Info().Str("fault", string(fault)).Str("response", resp).Msg("Injecting fault.")
+	switch fault {
+	case scenario.FaultDrop:
+		return errConnectionDropped
+	case scenario.FaultPartial:
+		if err := l.write(connection, resp[:len(resp)/2]); err != nil {
+			return err
+		}
+		return errConnectionDropped
+	case scenario.FaultMalformed:
+		// the response without its reason field
+		return l.write(connection, resp[:strings.LastIndex(resp, "|")]+"\n")
+	case scenario.FaultDuplicate:
+		if err := l.sendResponse(connection, resp); err != nil {
+			return err
+		}
+		return l.sendResponse(connection, resp)
+	case scenario.FaultNoNewline:
+		return l.write(connection, resp)
+	case scenario.FaultStall:
+		<-l.ctx.Done()
+		return errConnectionDropped
+	default:
+		return l.sendResponse(connection, resp)
+	}
+}
+
+func (l *TcpListener) write(connection *connection, data string) error {
+	connection.writeMu.Lock()
+	defer connection.writeMu.Unlock()
+	var err error
+	if l.options.Timeouts.Write > 0 {
+		err = connection.SetWriteDeadline(time.Now().Add(l.options.Timeouts.Write))
+	}
+	if err == nil {
+		_, err = io.WriteString(connection, l.numberLine(connection, data))
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		l.logClosing(connection, errWriteTimeout)
+		return fmt.Errorf("%w: %w", errWriteTimeout, err)
+	}
+	if err != nil {
+		l.deps.Logger.Error().Err(err).Msg("Error writing response to connection.")
+	}
+	return err
+}
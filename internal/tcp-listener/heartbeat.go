@@ -0,0 +1,64 @@
+package tcp_listener
+
+import (
+	"errors"
+	"github.com/form3tech-oss/interview-simulator/internal/response"
+	"time"
+)
+
+const (
+	// echoRequest is answered with RESPONSE|ECHO|<timestamp>, to check the connection is
+	// alive.
+	echoRequest = "ECHO"
+	echoStatus  = "ECHO"
+	// heartbeatRequest acknowledges a HEARTBEAT|<timestamp> sent by the simulator. It is
+	// never tagged with a correlation id, and gets no response.
+	heartbeatRequest = "HEARTBEAT"
+)
+
+var errHeartbeatTimeout = errors.New("heartbeat not acknowledged")
+
+func (l *TcpListener) echo() response.Response {
+	return response.Response{Status: echoStatus, Reason: l.timestamp()}
+}
+
+func (l *TcpListener) timestamp() string {
+	return l.deps.Clock.Now().UTC().Format(time.RFC3339Nano)
+}
+
+// acknowledgeHeartbeat records a line received on the connection, returning true if it
+// was a heartbeat acknowledgement.
+func (l *TcpListener) acknowledgeHeartbeat(connection *connection, request string) bool {
+	connection.received.Store(true)
+	if request != heartbeatRequest {
+		return false
+	}
+	connection.awaitingAck.Store(false)
+	return true
+}
+
+// superviseConnection sends a heartbeat to the connection at every interval it is idle,
+// and closes it if the heartbeat isn't acknowledged by the next interval. It returns
+// once stop is closed.
+func (l *TcpListener) superviseConnection(connection *connection, stop <-chan struct{}) {
+	for {
+		select {
+		case <-l.deps.Clock.After(l.options.Heartbeat):
+		case <-stop:
+			return
+		}
+
+		if connection.awaitingAck.Load() {
+			l.logClosing(connection, errHeartbeatTimeout)
+			l.deleteAndCloseConnection(connection)
+			return
+		}
+		if connection.received.Swap(false) || connection.isActive() {
+			continue
+		}
+		connection.awaitingAck.Store(true)
+		if l.sendResponse(connection, heartbeatRequest+"|"+l.timestamp()) != nil {
+			return
+		}
+	}
+}
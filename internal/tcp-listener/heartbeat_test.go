@@ -0,0 +1,100 @@
+package tcp_listener_test
+
+import (
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/clock"
+	"github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"github.com/rs/zerolog"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const HEARTBEAT = 10 * time.Second
+
+type HeartbeatTestSuite struct {
+	suite.Suite
+	clock *clock.Fake
+	logs  *logSink
+	conn  *testConn
+}
+
+func TestHeartbeatSuite(t *testing.T) {
+	suite.Run(t, &HeartbeatTestSuite{})
+}
+
+func (suite *HeartbeatTestSuite) SetupTest() {
+	suite.clock = clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	suite.logs = &logSink{}
+	logger := zerolog.New(suite.logs).With().Timestamp().Logger()
+	listener := startListener(suite.T(), tcp_listener.Options{Heartbeat: HEARTBEAT}, tcp_listener.TcpListenerDeps{Logger: logger, Clock: suite.clock})
+	suite.conn = dial(suite.T(), listener.Addr().String())
+}
+
+func (suite *HeartbeatTestSuite) read() string {
+	line, err := suite.conn.read()
+	suite.NoError(err, "Failed to read line")
+	return line
+}
+
+// heartbeat waits for the connection to be supervised and advances the clock to the
+// next heartbeat.
+func (suite *HeartbeatTestSuite) heartbeat() {
+	suite.clock.BlockUntil(1)
+	suite.clock.Advance(HEARTBEAT)
+}
+
+func (suite *HeartbeatTestSuite) Test_Echo() {
+	suite.conn.write("ECHO")
+
+	suite.Equal("RESPONSE|ECHO|2024-01-01T00:00:00Z", suite.read())
+}
+
+func (suite *HeartbeatTestSuite) Test_PipelinedEcho() {
+	suite.conn.write("PIPELINE")
+	suite.Equal("RESPONSE|ACCEPTED|Pipelining enabled", suite.read())
+	suite.conn.write("a|ECHO")
+
+	suite.Equal("a|RESPONSE|ECHO|2024-01-01T00:00:00Z", suite.read())
+}
+
+func (suite *HeartbeatTestSuite) Test_AcknowledgedHeartbeats() {
+	for i := range 3 {
+		suite.heartbeat()
+		suite.Equal(fmt.Sprintf("HEARTBEAT|2024-01-01T00:00:%dZ", i*20+10), suite.read())
+		suite.conn.write("HEARTBEAT")
+		// the echo response ensures the acknowledgement was received
+		suite.conn.write("ECHO")
+		suite.read()
+		// the echo makes the connection busy for the next interval
+		suite.heartbeat()
+	}
+}
+
+func (suite *HeartbeatTestSuite) Test_UnacknowledgedHeartbeatClosesConnection() {
+	suite.heartbeat()
+	suite.True(strings.HasPrefix(suite.read(), "HEARTBEAT|"))
+
+	suite.heartbeat()
+
+	_, err := suite.conn.read()
+	suite.ErrorIs(err, io.EOF, "Connection should be closed")
+	suite.Eventually(func() bool {
+		return strings.Contains(suite.logs.All(), `"reason":"heartbeat not acknowledged"`)
+	}, time.Second, 10*time.Millisecond, "Reason was not logged")
+}
+
+func (suite *HeartbeatTestSuite) Test_RequestsDoNotAcknowledgeHeartbeats() {
+	suite.heartbeat()
+	suite.True(strings.HasPrefix(suite.read(), "HEARTBEAT|"))
+	suite.conn.write("PAYMENT|10")
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", suite.read())
+
+	suite.heartbeat()
+
+	_, err := suite.conn.read()
+	suite.ErrorIs(err, io.EOF, "Connection should be closed")
+}
@@ -0,0 +1,80 @@
+package tcp_listener
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/response"
+)
+
+// DefaultMaxRequestSize is the maximum length of a request line, without the newline.
+const DefaultMaxRequestSize = bufio.MaxScanTokenSize
+
+// OversizedPolicy decides what happens to the requests longer than the maximum size.
+type OversizedPolicy string
+
+const (
+	// OversizedReject responds RESPONSE|REJECTED|Invalid request and keeps reading.
+	OversizedReject OversizedPolicy = "reject"
+	// OversizedClose closes the connection.
+	OversizedClose OversizedPolicy = "close"
+)
+
+// oversizedRequest replaces the oversized lines returned by the scanner. It can't be a
+// request, as requests never contain a newline.
+const oversizedRequest = "\n"
+
+var errRequestTooLong = errors.New("request too long")
+
+func (p OversizedPolicy) validate() error {
+	switch p {
+	case OversizedReject, OversizedClose:
+		return nil
+	}
+	return fmt.Errorf("unknown oversized request policy %q", p)
+}
+
+// splitLines splits lines like bufio.ScanLines, replacing the lines longer than maxSize with
+// oversizedRequest. The rest of an oversized line is discarded as it is read, so the
+// scanner never buffers more than maxSize bytes.
+func splitLines(maxSize int) bufio.SplitFunc {
+	discarding := false
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if discarding {
+			if i := bytes.IndexByte(data, '\n'); i >= 0 {
+				discarding = false
+				return i + 1, []byte(oversizedRequest), nil
+			}
+			if atEOF {
+				discarding = false
+				return len(data), []byte(oversizedRequest), nil
+			}
+			return len(data), nil, nil
+		}
+
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if len(token) > maxSize {
+			return advance, []byte(oversizedRequest), nil
+		}
+		// leave room for a \r before the newline
+		if advance == 0 && len(data) > maxSize+1 {
+			discarding = true
+			return len(data), nil, nil
+		}
+		return advance, token, err
+	}
+}
+
+// handleOversizedRequest applies the oversized request policy. It returns false if the
+// connection must be closed.
+func (l *TcpListener) handleOversizedRequest(connection *connection) bool {
+	l.deps.Metrics.OversizedRequests.Inc(l.address)
+	if l.options.OversizedRequests == OversizedClose {
+		l.logClosing(connection, errRequestTooLong)
+		return false
+	}
+	l.deps.Logger.Debug().Uint64("connection", connection.id).Msg("Rejecting oversized request.")
+	invalid := response.NewRejected("Invalid request")
+	return l.sendResponse(connection, invalid.ToString()) == nil
+}
@@ -0,0 +1,76 @@
+package tcp_listener_test
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/metrics"
+	"github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"github.com/rs/zerolog"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type OversizedTestSuite struct {
+	suite.Suite
+	logs    *logSink
+	metrics *metrics.Listener
+	address string
+}
+
+func TestOversizedSuite(t *testing.T) {
+	suite.Run(t, &OversizedTestSuite{})
+}
+
+func (suite *OversizedTestSuite) start(maxSize int, policy tcp_listener.OversizedPolicy) *testConn {
+	suite.logs = &logSink{}
+	suite.metrics = metrics.NewListener(metrics.NewRegistry())
+	logger := zerolog.New(suite.logs).With().Timestamp().Logger()
+	listener := startListener(suite.T(), tcp_listener.Options{MaxRequestSize: maxSize, OversizedRequests: policy}, tcp_listener.TcpListenerDeps{Logger: logger, Metrics: suite.metrics})
+	suite.address = listener.Addr().String()
+	return dial(suite.T(), suite.address)
+}
+
+func (suite *OversizedTestSuite) Test_OversizedRequestIsRejected() {
+	conn := suite.start(len("PAYMENT|10"), "")
+
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", conn.send("PAYMENT|10"))
+	suite.Equal("RESPONSE|REJECTED|Invalid request", conn.send("PAYMENT|100"))
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", conn.send("PAYMENT|20"), "Connection should keep processing requests")
+	suite.Equal(1.0, suite.metrics.OversizedRequests.Value(suite.address))
+}
+
+func (suite *OversizedTestSuite) Test_RequestLongerThanTheReadBuffer() {
+	conn := suite.start(0, tcp_listener.OversizedReject)
+
+	suite.Equal("RESPONSE|REJECTED|Invalid request", conn.send("PAYMENT|"+strings.Repeat("1", 1<<20)))
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", conn.send("PAYMENT|10"))
+}
+
+func (suite *OversizedTestSuite) Test_PipelinedOversizedRequestIsRejected() {
+	conn := suite.start(len("a|PAYMENT|10"), tcp_listener.OversizedReject)
+
+	suite.Equal("RESPONSE|ACCEPTED|Pipelining enabled", conn.send("PIPELINE"))
+	suite.Equal("RESPONSE|REJECTED|Invalid request", conn.send("a|PAYMENT|100"))
+	suite.Equal("b|RESPONSE|ACCEPTED|Transaction processed", conn.send("b|PAYMENT|10"))
+}
+
+func (suite *OversizedTestSuite) Test_OversizedRequestClosesConnection() {
+	conn := suite.start(len("PAYMENT|10"), tcp_listener.OversizedClose)
+
+	conn.write("PAYMENT|100")
+
+	_, err := conn.read()
+	suite.ErrorIs(err, io.EOF, "Connection should be closed")
+	suite.Equal(1.0, suite.metrics.OversizedRequests.Value(suite.address))
+	suite.Eventually(func() bool {
+		return strings.Contains(suite.logs.All(), `"reason":"request too long"`)
+	}, time.Second, 10*time.Millisecond, "Reason was not logged")
+}
+
+func (suite *OversizedTestSuite) Test_UnknownPolicy() {
+	_, err := tcp_listener.New("localhost:0", 0, tcp_listener.Options{OversizedRequests: "truncate"}, &tcp_listener.TcpListenerDeps{Logger: zerolog.Nop()})
+
+	suite.ErrorContains(err, `unknown oversized request policy "truncate"`)
+}
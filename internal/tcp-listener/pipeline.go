@@ -0,0 +1,63 @@
+package tcp_listener
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/journal"
+	"github.com/form3tech-oss/interview-simulator/internal/response"
+	"github.com/form3tech-oss/interview-simulator/internal/session"
+	"strings"
+	"time"
+)
+
+// pipelineRequest switches a connection to pipelined mode. Pipelined requests are sent as
+// <correlation-id>|<request> without waiting for the previous responses, and are
+// processed concurrently. Their responses are sent as they complete, possibly out of
+// order, as <correlation-id>|<response>.
+const pipelineRequest = "PIPELINE"
+
+func (l *TcpListener) enablePipelining(connection *connection, receivedAt time.Time) error {
+	resp := response.NewAccepted("Pipelining enabled")
+	l.mu.Lock()
+	connection.pipelined = true
+	l.mu.Unlock()
+	l.deps.Logger.Info().Uint64("connection", connection.id).Msg("Pipelining enabled.")
+	err := l.sendResponse(connection, resp.ToString())
+	l.record(connection, journal.NewEntry(connection.id, pipelineRequest, resp.ToString(), "", receivedAt, l.deps.Clock.Now()))
+	return err
+}
+
+// handlePipelinedRequest starts processing the request in the background. It returns
+// false if the request was discarded and the connection must be closed.
+func (l *TcpListener) handlePipelinedRequest(connection *connection, request string, receivedAt time.Time) bool {
+	correlationID, request, found := strings.Cut(request, "|")
+	if !found || correlationID == "" {
+		invalid := response.NewRejected("Invalid request")
+		return l.sendResponse(connection, invalid.ToString()) == nil
+	}
+	// a tagged logon is not a session request, but its secret must not be logged or
+	// recorded either
+	request = session.Redact(request)
+	l.deps.Logger.Debug().Str("request", tag(correlationID, request)).Msg("Received request.")
+
+	id, ok := l.requests.add(connection, request)
+	if !ok {
+		l.deps.Logger.Debug().Str("request", request).Msg("Discarding request.")
+		return false
+	}
+
+	connection.pending.Add(1)
+	go func() {
+		defer connection.pending.Done()
+		err := l.processRequest(connection, id, correlationID, request, receivedAt)
+		// while draining, the last request to complete closes the connection
+		if err != nil || (l.requests.isDraining() && !connection.isActive()) {
+			l.deleteAndCloseConnection(connection)
+			return
+		}
+		l.idleDeadline(connection)
+	}()
+	return true
+}
+
+func tag(correlationID string, line string) string {
+	return correlationID + "|" + line
+}
@@ -0,0 +1,114 @@
+package tcp_listener
+
+import (
+	"errors"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/ratelimit"
+	"github.com/form3tech-oss/interview-simulator/internal/response"
+	"time"
+)
+
+// RateLimitPolicy decides what happens to the requests over the rate limits.
+type RateLimitPolicy string
+
+const (
+	// RateLimitReject rejects the requests with the rate limit reason.
+	RateLimitReject RateLimitPolicy = "reject"
+	// RateLimitDelay holds the requests until the rate limits allow them.
+	RateLimitDelay RateLimitPolicy = "delay"
+)
+
+const DefaultRateLimitReason = "Rate limit exceeded"
+
+// RateLimit throttles the requests with token buckets, refilled at the rates in requests
+// per second. Zero disables a limit. The participants can have their own rate limits too.
+type RateLimit struct {
+	PerConnection float64
+	Global        float64
+	// Burst is the number of requests allowed at once by each limit. Defaults to 1.
+	Burst int
+	// Policy defaults to RateLimitReject.
+	Policy RateLimitPolicy
+	// Reason of the rejected requests. Defaults to "Rate limit exceeded".
+	Reason string
+}
+
+func (r *RateLimit) setDefaults() {
+	if r.Policy == "" {
+		r.Policy = RateLimitReject
+	}
+	if r.Reason == "" {
+		r.Reason = DefaultRateLimitReason
+	}
+}
+
+func (r RateLimit) validate() error {
+	if r.PerConnection < 0 || r.Global < 0 {
+		return errors.New("rate limits can't be negative")
+	}
+	switch r.Policy {
+	case RateLimitReject, RateLimitDelay:
+		return nil
+	}
+	return fmt.Errorf("unknown rate limit policy %q", r.Policy)
+}
+
+// newBucket returns nil for a disabled limit.
+func (l *TcpListener) newBucket(rate float64) *ratelimit.Bucket {
+	if rate == 0 {
+		return nil
+	}
+	return ratelimit.NewBucket(rate, l.options.RateLimit.Burst, l.deps.Clock)
+}
+
+// participantLimiter returns the rate limit of the participant logged on the connection,
+// nil if there is none.
+func (l *TcpListener) participantLimiter(connection *connection) *ratelimit.Bucket {
+	participant := connection.participant.Load()
+	if participant == nil || participant.RateLimit == 0 {
+		return nil
+	}
+	l.participantLimitersMu.Lock()
+	defer l.participantLimitersMu.Unlock()
+	limiter, ok := l.participantLimiters[participant.ID]
+	if !ok {
+		limiter = l.newBucket(participant.RateLimit)
+		l.participantLimiters[participant.ID] = limiter
+	}
+	return limiter
+}
+
+// throttle applies the rate limits to a request of the connection. It returns true with
+// the response if the request must not be processed.
+func (l *TcpListener) throttle(connection *connection) (response.Response, bool) {
+	limit := l.options.RateLimit
+	participantLimiter := l.participantLimiter(connection)
+	if connection.limiter == nil && participantLimiter == nil && l.limiter == nil {
+		return response.Response{}, false
+	}
+
+	if limit.Policy == RateLimitReject {
+		if ratelimit.AllowAll(connection.limiter, participantLimiter, l.limiter) {
+			return response.Response{}, false
+		}
+		l.deps.Metrics.ThrottledRequests.Inc(l.address)
+		return response.NewRejected(limit.Reason), true
+	}
+
+	var wait time.Duration
+	for _, limiter := range []*ratelimit.Bucket{connection.limiter, participantLimiter, l.limiter} {
+		if limiter != nil {
+			wait = max(wait, limiter.Reserve())
+		}
+	}
+	if wait == 0 {
+		return response.Response{}, false
+	}
+	l.deps.Metrics.ThrottledRequests.Inc(l.address)
+	select {
+	case <-l.deps.Clock.After(wait):
+		return response.Response{}, false
+	case <-l.ctx.Done():
+		return response.NewRejected("Cancelled"), true
+	}
+}
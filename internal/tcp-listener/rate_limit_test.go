@@ -0,0 +1,111 @@
+package tcp_listener_test
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/clock"
+	"github.com/form3tech-oss/interview-simulator/internal/metrics"
+	"github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"github.com/rs/zerolog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimitTestSuite struct {
+	suite.Suite
+	listener *tcp_listener.TcpListener
+	clock    *clock.Fake
+	metrics  *metrics.Listener
+}
+
+func TestRateLimitSuite(t *testing.T) {
+	suite.Run(t, &RateLimitTestSuite{})
+}
+
+func (suite *RateLimitTestSuite) start(limit tcp_listener.RateLimit) {
+	suite.clock = clock.NewFake(time.Now())
+	suite.metrics = metrics.NewListener(metrics.NewRegistry())
+	suite.listener = startListener(suite.T(), tcp_listener.Options{RateLimit: limit}, tcp_listener.TcpListenerDeps{Clock: suite.clock, Metrics: suite.metrics})
+}
+
+func (suite *RateLimitTestSuite) dial() *testConn {
+	return dial(suite.T(), suite.listener.Addr().String())
+}
+
+func (suite *RateLimitTestSuite) Test_PerConnectionLimit() {
+	suite.start(tcp_listener.RateLimit{PerConnection: 1, Burst: 2})
+	conn := suite.dial()
+
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", conn.send("PAYMENT|10"))
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", conn.send("PAYMENT|10"))
+	suite.Equal("RESPONSE|REJECTED|Rate limit exceeded", conn.send("PAYMENT|10"))
+
+	other := suite.dial()
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", other.send("PAYMENT|10"), "Other connections should have their own limit")
+
+	suite.clock.Advance(time.Second)
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", conn.send("PAYMENT|10"), "Limit should refill over time")
+	suite.Equal(1.0, suite.metrics.ThrottledRequests.Value(suite.listener.Addr().String()))
+}
+
+func (suite *RateLimitTestSuite) Test_GlobalLimit() {
+	suite.start(tcp_listener.RateLimit{Global: 1, Reason: "Slow down"})
+	conn := suite.dial()
+	other := suite.dial()
+
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", conn.send("PAYMENT|10"))
+	suite.Equal("RESPONSE|REJECTED|Slow down", other.send("PAYMENT|10"))
+}
+
+func (suite *RateLimitTestSuite) Test_GlobalRejectionKeepsTheConnectionLimit() {
+	suite.start(tcp_listener.RateLimit{PerConnection: 1, Global: 10})
+	conn := suite.dial()
+	other := suite.dial()
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", conn.send("PAYMENT|10"))
+	suite.Equal("RESPONSE|REJECTED|Rate limit exceeded", other.send("PAYMENT|10"))
+
+	// refills the global limit only
+	suite.clock.Advance(100 * time.Millisecond)
+
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", other.send("PAYMENT|10"))
+}
+
+func (suite *RateLimitTestSuite) Test_RequestsOverTheLimitAreDelayed() {
+	suite.start(tcp_listener.RateLimit{PerConnection: 2, Policy: tcp_listener.RateLimitDelay})
+	conn := suite.dial()
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", conn.send("PAYMENT|10"))
+
+	responses := make(chan string, 1)
+	go func() { responses <- conn.send("PAYMENT|10") }()
+	suite.clock.BlockUntil(1)
+	suite.clock.Advance(499 * time.Millisecond)
+	suite.Equal(1, suite.clock.Waiters(), "Request should be delayed until the limit allows it")
+	suite.clock.Advance(time.Millisecond)
+
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", <-responses)
+	suite.Equal(1.0, suite.metrics.ThrottledRequests.Value(suite.listener.Addr().String()))
+}
+
+func (suite *RateLimitTestSuite) Test_DelayedRequestsAreCancelledOnShutdown() {
+	suite.start(tcp_listener.RateLimit{PerConnection: 1, Policy: tcp_listener.RateLimitDelay})
+	conn := suite.dial()
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", conn.send("PAYMENT|10"))
+
+	responses := make(chan string, 1)
+	go func() { responses <- conn.send("PAYMENT|10") }()
+	suite.clock.BlockUntil(1)
+	suite.listener.Stop()
+
+	suite.Equal("RESPONSE|REJECTED|Cancelled", <-responses)
+}
+
+func (suite *RateLimitTestSuite) Test_InvalidRateLimit() {
+	for limit, expected := range map[tcp_listener.RateLimit]string{
+		{Global: 1, Policy: "drop"}: `unknown rate limit policy "drop"`,
+		{PerConnection: -1}:         "rate limits can't be negative",
+	} {
+		_, err := tcp_listener.New("localhost:0", 0, tcp_listener.Options{RateLimit: limit}, &tcp_listener.TcpListenerDeps{Logger: zerolog.Nop()})
+
+		suite.ErrorContains(err, expected)
+	}
+}
@@ -0,0 +1,102 @@
+package tcp_listener
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/clock"
+	"sort"
+	"sync"
+	"time"
+)
+
+type inFlightRequest struct {
+	connection *connection
+	request    string
+	startedAt  time.Time
+}
+
+// requestRegistry tracks the requests that have been accepted but whose response
+// hasn't been sent yet.
+type requestRegistry struct {
+	mu       sync.Mutex
+	nextID   uint64
+	requests map[uint64]inFlightRequest
+	draining bool
+	drained  chan struct{}
+	clock    clock.Clock
+}
+
+func newRequestRegistry(clk clock.Clock) *requestRegistry {
+	return &requestRegistry{
+		requests: make(map[uint64]inFlightRequest),
+		drained:  make(chan struct{}),
+		clock:    clk,
+	}
+}
+
+// add registers a request received on the connection. It returns false if the registry
+// is draining and the request must not be accepted.
+func (r *requestRegistry) add(connection *connection, request string) (uint64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.draining {
+		return 0, false
+	}
+	r.nextID++
+	r.requests[r.nextID] = inFlightRequest{connection: connection, request: request, startedAt: r.clock.Now()}
+	connection.inFlight.Add(1)
+	return r.nextID, true
+}
+
+func (r *requestRegistry) remove(id uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if request, ok := r.requests[id]; ok {
+		request.connection.inFlight.Add(-1)
+		delete(r.requests, id)
+	}
+	r.closeIfDrained()
+}
+
+// drain stops accepting new requests. The returned channel is closed once all the
+// in-flight requests are completed.
+func (r *requestRegistry) drain() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.draining {
+		r.draining = true
+		r.closeIfDrained()
+	}
+	return r.drained
+}
+
+func (r *requestRegistry) isDraining() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.draining
+}
+
+func (r *requestRegistry) list() []RequestInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	requests := make([]RequestInfo, 0, len(r.requests))
+	for id, request := range r.requests {
+		requests = append(requests, RequestInfo{
+			ID:           id,
+			ConnectionID: request.connection.id,
+			Request:      request.request,
+			StartedAt:    request.startedAt,
+		})
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].ID < requests[j].ID })
+	return requests
+}
+
+func (r *requestRegistry) closeIfDrained() {
+	if !r.draining || len(r.requests) > 0 {
+		return
+	}
+	select {
+	case <-r.drained:
+	default:
+		close(r.drained)
+	}
+}
@@ -0,0 +1,94 @@
+package tcp_listener
+
+import (
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/response"
+	"strconv"
+	"strings"
+)
+
+// GapPolicy decides what happens to the requests received after a gap in the sequence.
+type GapPolicy string
+
+const (
+	// GapsReject rejects the requests with the gap reason.
+	GapsReject GapPolicy = "reject"
+	// GapsResend asks the client to resend the missing requests with
+	// RESEND|<first-missing>|<last-missing>.
+	GapsResend GapPolicy = "resend"
+)
+
+const DefaultGapReason = "Sequence gap"
+
+// Sequencing numbers the lines sent on each connection. Requests are sent as
+// <sequence-number>|<request>, numbered from 1, and every line sent by the simulator
+// starts with its own sequence number. Requests after a gap, duplicates and requests
+// without a valid sequence number are not processed.
+type Sequencing struct {
+	Enabled bool
+	// Gaps defaults to GapsReject.
+	Gaps GapPolicy
+	// Reason of the requests rejected after a gap. Defaults to "Sequence gap".
+	Reason string
+}
+
+func (s *Sequencing) setDefaults() {
+	if s.Gaps == "" {
+		s.Gaps = GapsReject
+	}
+	if s.Reason == "" {
+		s.Reason = DefaultGapReason
+	}
+}
+
+func (s Sequencing) validate() error {
+	switch s.Gaps {
+	case GapsReject, GapsResend:
+		return nil
+	}
+	return fmt.Errorf("unknown sequence gap policy %q", s.Gaps)
+}
+
+// sequence checks the sequence number of the request and strips it. It returns whether
+// the request was handled because it is out of sequence, and false if the connection must
+// be closed.
+func (l *TcpListener) sequence(connection *connection, request string) (string, bool, bool) {
+	number, rest, _ := strings.Cut(request, "|")
+	received, err := strconv.ParseUint(number, 10, 64)
+	expected := connection.receivedSeq + 1
+
+	var line, sequenceError string
+	switch {
+	case err != nil || received == 0:
+		sequenceError = "invalid"
+		invalid := response.NewRejected("Invalid sequence number")
+		line = invalid.ToString()
+	case received == expected:
+		connection.receivedSeq = received
+		return rest, false, true
+	case received < expected:
+		sequenceError = "duplicate"
+		duplicate := response.NewRejected("Duplicate sequence number")
+		line = duplicate.ToString()
+	case l.options.Sequencing.Gaps == GapsResend:
+		sequenceError = "gap"
+		line = fmt.Sprintf("RESEND|%d|%d", expected, received-1)
+	default:
+		sequenceError = "gap"
+		gap := response.NewRejected(l.options.Sequencing.Reason)
+		line = gap.ToString()
+	}
+	l.deps.Metrics.SequenceErrors.Inc(l.address, sequenceError)
+	l.deps.Logger.Info().Uint64("connection", connection.id).Uint64("expected", expected).Str("received", number).Msg("Request out of sequence.")
+	return "", true, l.sendResponse(connection, line) == nil
+}
+
+// numberLine prefixes a line sent on the connection with its sequence number. It must
+// be called holding the write mutex of the connection.
+func (l *TcpListener) numberLine(connection *connection, data string) string {
+	if !l.options.Sequencing.Enabled {
+		return data
+	}
+	connection.sentSeq++
+	return strconv.FormatUint(connection.sentSeq, 10) + "|" + data
+}
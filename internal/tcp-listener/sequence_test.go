@@ -0,0 +1,83 @@
+package tcp_listener_test
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/metrics"
+	"github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"github.com/rs/zerolog"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SequenceTestSuite struct {
+	suite.Suite
+	metrics *metrics.Listener
+	address string
+	conn    *testConn
+}
+
+func TestSequenceSuite(t *testing.T) {
+	suite.Run(t, &SequenceTestSuite{})
+}
+
+func (suite *SequenceTestSuite) start(sequencing tcp_listener.Sequencing) {
+	suite.metrics = metrics.NewListener(metrics.NewRegistry())
+	listener := startListener(suite.T(), tcp_listener.Options{Sequencing: sequencing}, tcp_listener.TcpListenerDeps{Metrics: suite.metrics})
+	suite.address = listener.Addr().String()
+	suite.conn = dial(suite.T(), suite.address)
+}
+
+func (suite *SequenceTestSuite) Test_RequestsAndResponsesAreNumbered() {
+	suite.start(tcp_listener.Sequencing{Enabled: true})
+
+	suite.Equal("1|RESPONSE|ACCEPTED|Transaction processed", suite.conn.send("1|PAYMENT|10"))
+	suite.Equal("2|RESPONSE|ACCEPTED|Pipelining enabled", suite.conn.send("2|PIPELINE"))
+	suite.Equal("3|a|RESPONSE|ACCEPTED|Transaction processed", suite.conn.send("3|a|PAYMENT|10"))
+}
+
+func (suite *SequenceTestSuite) Test_GapIsRejected() {
+	suite.start(tcp_listener.Sequencing{Enabled: true, Reason: "Out of sequence"})
+
+	suite.Equal("1|RESPONSE|ACCEPTED|Transaction processed", suite.conn.send("1|PAYMENT|10"))
+	suite.Equal("2|RESPONSE|REJECTED|Out of sequence", suite.conn.send("3|PAYMENT|10"))
+	suite.Equal("3|RESPONSE|ACCEPTED|Transaction processed", suite.conn.send("2|PAYMENT|10"))
+	suite.Equal("4|RESPONSE|ACCEPTED|Transaction processed", suite.conn.send("3|PAYMENT|10"))
+	suite.Equal(1.0, suite.metrics.SequenceErrors.Value(suite.address, "gap"))
+}
+
+func (suite *SequenceTestSuite) Test_GapRequestsResend() {
+	suite.start(tcp_listener.Sequencing{Enabled: true, Gaps: tcp_listener.GapsResend})
+
+	suite.Equal("1|RESEND|1|3", suite.conn.send("4|PAYMENT|10"))
+	suite.Equal("2|RESPONSE|ACCEPTED|Transaction processed", suite.conn.send("1|PAYMENT|10"))
+}
+
+func (suite *SequenceTestSuite) Test_DuplicateIsRejected() {
+	suite.start(tcp_listener.Sequencing{Enabled: true, Gaps: tcp_listener.GapsResend})
+
+	suite.Equal("1|RESPONSE|ACCEPTED|Transaction processed", suite.conn.send("1|PAYMENT|10"))
+	suite.Equal("2|RESPONSE|REJECTED|Duplicate sequence number", suite.conn.send("1|PAYMENT|10"))
+	suite.Equal("3|RESPONSE|ACCEPTED|Transaction processed", suite.conn.send("2|PAYMENT|10"))
+	suite.Equal(1.0, suite.metrics.SequenceErrors.Value(suite.address, "duplicate"))
+}
+
+func (suite *SequenceTestSuite) Test_InvalidSequenceNumberIsRejected() {
+	suite.start(tcp_listener.Sequencing{Enabled: true})
+
+	suite.Equal("1|RESPONSE|REJECTED|Invalid sequence number", suite.conn.send("PAYMENT|10"))
+	suite.Equal("2|RESPONSE|REJECTED|Invalid sequence number", suite.conn.send("0|PAYMENT|10"))
+	suite.Equal("3|RESPONSE|ACCEPTED|Transaction processed", suite.conn.send("1|PAYMENT|10"))
+	suite.Equal(2.0, suite.metrics.SequenceErrors.Value(suite.address, "invalid"))
+}
+
+func (suite *SequenceTestSuite) Test_DisabledByDefault() {
+	suite.start(tcp_listener.Sequencing{})
+
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", suite.conn.send("PAYMENT|10"))
+}
+
+func (suite *SequenceTestSuite) Test_UnknownGapPolicy() {
+	_, err := tcp_listener.New("localhost:0", 0, tcp_listener.Options{Sequencing: tcp_listener.Sequencing{Enabled: true, Gaps: "ignore"}}, &tcp_listener.TcpListenerDeps{Logger: zerolog.Nop()})
+
+	suite.ErrorContains(err, `unknown sequence gap policy "ignore"`)
+}
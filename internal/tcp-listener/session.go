@@ -0,0 +1,80 @@
+package tcp_listener
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/journal"
+	"github.com/form3tech-oss/interview-simulator/internal/response"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	"github.com/form3tech-oss/interview-simulator/internal/session"
+	"strings"
+	"time"
+)
+
+// With participants configured, connections must start a session with
+// LOGON|<participant-id>|<secret> before sending other requests, and end it with LOGOFF.
+// Both are never tagged with a correlation id.
+const (
+	logonRequest  = session.LogonRequest
+	logoffRequest = "LOGOFF"
+)
+
+// handleSession handles the session requests, and rejects the other requests sent before
+// logon. It returns whether the request was handled, and false if the connection must be
+// closed.
+func (l *TcpListener) handleSession(connection *connection, request string, receivedAt time.Time) (bool, bool) {
+	if l.options.Participants == nil {
+		return false, true
+	}
+	participant := connection.participant.Load()
+
+	var resp response.Response
+	kind, _, _ := strings.Cut(request, "|")
+	switch {
+	case kind == logonRequest:
+		resp = l.logon(connection, request)
+		request = session.Redact(request)
+	case participant == nil:
+		resp = response.NewRejected("Not logged on")
+	case request == logoffRequest:
+		l.deps.Logger.Info().Uint64("connection", connection.id).Str("participant", participant.ID).Msg("Session ended.")
+		_ = l.respondSession(connection, request, response.NewAccepted("Logged off"), receivedAt)
+		return true, false
+	default:
+		return false, true
+	}
+	return true, l.respondSession(connection, request, resp, receivedAt) == nil
+}
+
+func (l *TcpListener) logon(connection *connection, request string) response.Response {
+	if connection.participant.Load() != nil {
+		return response.NewRejected("Already logged on")
+	}
+	parts := strings.Split(request, "|")
+	if len(parts) != 3 {
+		return response.NewRejected("Invalid request")
+	}
+	participant, ok := l.options.Participants.Authenticate(parts[1], parts[2])
+	if !ok {
+		l.deps.Logger.Info().Uint64("connection", connection.id).Str("participant", parts[1]).Msg("Rejecting logon.")
+		return response.NewRejected("Invalid credentials")
+	}
+	connection.participant.Store(participant)
+	l.deps.Logger.Info().Uint64("connection", connection.id).Str("participant", participant.ID).Msg("Session started.")
+	return response.NewAccepted("Logged on")
+}
+
+func (l *TcpListener) respondSession(connection *connection, request string, resp response.Response, receivedAt time.Time) error {
+	err := l.sendResponse(connection, resp.ToString())
+	respondedAt := l.deps.Clock.Now()
+	l.observe(resp, respondedAt.Sub(receivedAt))
+	l.record(connection, journal.NewEntry(connection.id, request, resp.ToString(), "", receivedAt, respondedAt))
+	return err
+}
+
+// scenarioFor returns the scenario of the participant logged on the connection, or the
+// scenario of the listener.
+func (l *TcpListener) scenarioFor(connection *connection) *scenario.Scenario {
+	if participant := connection.participant.Load(); participant != nil && participant.Scenario != nil {
+		return participant.Scenario
+	}
+	return l.Scenario()
+}
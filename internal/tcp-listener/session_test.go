@@ -0,0 +1,149 @@
+package tcp_listener_test
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/journal"
+	"github.com/form3tech-oss/interview-simulator/internal/session"
+	"github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type SessionTestSuite struct {
+	suite.Suite
+	listener *tcp_listener.TcpListener
+	journal  *journal.Memory
+	logs     *logSink
+	conn     *testConn
+}
+
+func TestSessionSuite(t *testing.T) {
+	suite.Run(t, &SessionTestSuite{})
+}
+
+func (suite *SessionTestSuite) SetupTest() {
+	dir := suite.T().TempDir()
+	suite.Require().NoError(os.WriteFile(filepath.Join(dir, "bank-b.yaml"), []byte("rules: [{amounts: [13], status: REJECTED, reason: Unlucky}]"), 0o600))
+	path := filepath.Join(dir, "participants.yaml")
+	suite.Require().NoError(os.WriteFile(path, []byte(`
+participants:
+  - {id: bank-a, secret: s3cret}
+  - {id: bank-b, secret: other, scenario: bank-b.yaml}
+  - {id: bank-d, secret: limited, rateLimit: 0.001}
+`), 0o600))
+	participants, err := session.Load(path)
+	suite.Require().NoError(err)
+
+	suite.journal = journal.NewMemory()
+	suite.logs = &logSink{}
+	logger := zerolog.New(suite.logs).With().Timestamp().Logger()
+	suite.listener = startListener(suite.T(), tcp_listener.Options{Participants: participants}, tcp_listener.TcpListenerDeps{Logger: logger, Journal: suite.journal})
+	suite.conn = dial(suite.T(), suite.listener.Addr().String())
+}
+
+func (suite *SessionTestSuite) Test_RequestsBeforeLogonAreRejected() {
+	for _, request := range []string{"PAYMENT|10", "PIPELINE", "ECHO", "LOGOFF"} {
+		suite.Equal("RESPONSE|REJECTED|Not logged on", suite.conn.send(request), request)
+	}
+}
+
+func (suite *SessionTestSuite) Test_Logon() {
+	suite.Equal("RESPONSE|REJECTED|Invalid credentials", suite.conn.send("LOGON|bank-a|wrong"))
+	suite.Equal("RESPONSE|REJECTED|Invalid credentials", suite.conn.send("LOGON|bank-c|s3cret"))
+	suite.Equal("RESPONSE|REJECTED|Invalid request", suite.conn.send("LOGON|bank-a"))
+	suite.Equal("RESPONSE|ACCEPTED|Logged on", suite.conn.send("LOGON|bank-a|s3cret"))
+	suite.Equal("RESPONSE|REJECTED|Already logged on", suite.conn.send("LOGON|bank-b|other"))
+
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", suite.conn.send("PAYMENT|13"))
+	connections := suite.listener.Connections()
+	suite.Require().Len(connections, 1)
+	suite.Equal("bank-a", connections[0].Participant)
+}
+
+func (suite *SessionTestSuite) Test_ParticipantScenario() {
+	suite.Equal("RESPONSE|ACCEPTED|Logged on", suite.conn.send("LOGON|bank-b|other"))
+
+	suite.Equal("RESPONSE|REJECTED|Unlucky", suite.conn.send("PAYMENT|13"))
+}
+
+func (suite *SessionTestSuite) Test_ParticipantRateLimitIsSharedByItsConnections() {
+	suite.Equal("RESPONSE|ACCEPTED|Logged on", suite.conn.send("LOGON|bank-d|limited"))
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", suite.conn.send("PAYMENT|10"))
+
+	other := dial(suite.T(), suite.listener.Addr().String())
+	suite.Equal("RESPONSE|ACCEPTED|Logged on", other.send("LOGON|bank-d|limited"))
+	suite.Equal("RESPONSE|REJECTED|Rate limit exceeded", other.send("PAYMENT|10"))
+}
+
+func (suite *SessionTestSuite) Test_ParticipantsOnlySeeTheirOwnPayments() {
+	suite.Equal("RESPONSE|ACCEPTED|Logged on", suite.conn.send("LOGON|bank-a|s3cret"))
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", suite.conn.send("PAYMENT|p1|50"))
+
+	other := dial(suite.T(), suite.listener.Addr().String())
+	suite.Equal("RESPONSE|ACCEPTED|Logged on", other.send("LOGON|bank-b|other"))
+	suite.Equal("RESPONSE|UNKNOWN|Unknown payment", other.send("STATUS|p1"))
+	suite.Equal("RESPONSE|REJECTED|Unknown payment", other.send("REVERSAL|p1"))
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", other.send("PAYMENT|p1|70"))
+
+	suite.Equal("RESPONSE|ACCEPTED|Refund processed", suite.conn.send("REFUND|p1|50"))
+	suite.Equal("RESPONSE|REJECTED|Refund exceeds payment", other.send("REFUND|p1|71"))
+}
+
+func (suite *SessionTestSuite) Test_JournalHasParticipantsAndNoSecrets() {
+	suite.conn.send("LOGON|bank-a|wrong")
+	suite.conn.send("LOGON|bank-a|s3cret")
+	suite.conn.send("PAYMENT|10")
+
+	// the requests are recorded once responded
+	entries, ok := suite.journal.Wait(3, time.Second)
+	suite.Require().True(ok, "Requests were not recorded")
+	suite.Equal("LOGON|bank-a|***", entries[0].Request)
+	suite.Equal("", entries[0].Participant)
+	suite.Equal("LOGON|bank-a|***", entries[1].Request)
+	suite.Equal("bank-a", entries[1].Participant)
+	suite.Equal("PAYMENT|10", entries[2].Request)
+	suite.Equal("bank-a", entries[2].Participant)
+}
+
+func (suite *SessionTestSuite) Test_TaggedLogonSecretIsNotLoggedOrRecorded() {
+	suite.Equal("RESPONSE|ACCEPTED|Logged on", suite.conn.send("LOGON|bank-a|s3cret"))
+	suite.Equal("RESPONSE|ACCEPTED|Pipelining enabled", suite.conn.send("PIPELINE"))
+
+	suite.Equal("a|RESPONSE|REJECTED|Invalid request", suite.conn.send("a|LOGON|bank-a|s3cret"))
+
+	entries, ok := suite.journal.Wait(3, time.Second)
+	suite.Require().True(ok, "Requests were not recorded")
+	suite.Equal("a|LOGON|bank-a|***", entries[2].Request)
+	suite.NotContains(suite.logs.All(), "s3cret")
+}
+
+func (suite *SessionTestSuite) Test_LogoffClosesConnection() {
+	suite.Equal("RESPONSE|ACCEPTED|Logged on", suite.conn.send("LOGON|bank-a|s3cret"))
+
+	suite.Equal("RESPONSE|ACCEPTED|Logged off", suite.conn.send("LOGOFF"))
+
+	_, err := suite.conn.read()
+	suite.ErrorIs(err, io.EOF, "Connection should be closed")
+}
+
+func (suite *SessionTestSuite) Test_PipelinedLogoffCompletesRequestsInFlight() {
+	suite.Equal("RESPONSE|ACCEPTED|Logged on", suite.conn.send("LOGON|bank-a|s3cret"))
+	suite.Equal("RESPONSE|ACCEPTED|Pipelining enabled", suite.conn.send("PIPELINE"))
+
+	suite.conn.write("a|PAYMENT|200\nLOGOFF")
+
+	var responses []string
+	for range 2 {
+		response, err := suite.conn.read()
+		suite.NoError(err, "Failed to read response")
+		responses = append(responses, response)
+	}
+	suite.ElementsMatch([]string{"RESPONSE|ACCEPTED|Logged off", "a|RESPONSE|ACCEPTED|Transaction processed"}, responses)
+	_, err := suite.conn.read()
+	suite.ErrorIs(err, io.EOF, "Connection should be closed")
+}
@@ -2,14 +2,22 @@ package tcp_listener
 
 import (
 	"bufio"
+	"context"
 	"errors"
-	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/clock"
+	"github.com/form3tech-oss/interview-simulator/internal/journal"
+	"github.com/form3tech-oss/interview-simulator/internal/metrics"
 	"github.com/form3tech-oss/interview-simulator/internal/payment"
-	response "github.com/form3tech-oss/interview-simulator/internal/response"
+	"github.com/form3tech-oss/interview-simulator/internal/ratelimit"
+	"github.com/form3tech-oss/interview-simulator/internal/response"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	"github.com/form3tech-oss/interview-simulator/internal/session"
 	"github.com/rs/zerolog"
 	"io"
 	"net"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -24,13 +32,22 @@ func (s NetListener) Listen(network string, address string) (net.Listener, error
 }
 
 type newScanner interface {
-	NewScanner(r io.Reader) Scanner
+	NewScanner(r io.Reader, maxSize int) Scanner
 }
 
 type BufioScanner struct{}
 
-func (s BufioScanner) NewScanner(r io.Reader) Scanner {
-	return bufio.NewScanner(r)
+// NewScanner scans the lines of r, returning the lines longer than maxSize as
+// oversizedRequest.
+func (s BufioScanner) NewScanner(r io.Reader, maxSize int) Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, min(maxSize+2, 4096)), maxSize+2)
+	scanner.Split(splitLines(maxSize))
+	return scanner
+}
+
+type recorder interface {
+	Record(entry journal.Entry) error
 }
 
 type Scanner interface {
@@ -40,40 +57,147 @@ type Scanner interface {
 }
 
 type TcpListener struct {
-	wg               sync.WaitGroup
-	waitPeriod       time.Duration
+	// address is the bound address, identifying the listener in the metrics, the admin
+	// API and the journal.
+	address          string
+	waitPeriod       atomic.Int64
+	scenario         atomic.Pointer[scenario.Scenario]
 	mu               sync.Mutex
-	connections      map[net.Conn]struct{}
+	connections      map[*connection]struct{}
+	nextConnectionID uint64
+	requests         *requestRegistry
+	payments         *payment.Store
 	shutdownListener bool
+	stopOnce         sync.Once
 	listener         net.Listener
+	ctx              context.Context
+	cancel           context.CancelFunc
+	options          Options
 	deps             TcpListenerDeps
+	// stopping is closed when the listener stops accepting connections.
+	stopping chan struct{}
+	// slots limits the open connections when MaxConnections is set.
+	slots chan struct{}
+	// limiter is the global rate limit, nil if disabled.
+	limiter *ratelimit.Bucket
+	// participantLimiters are the rate limits of the participants, created on their first
+	// request.
+	participantLimitersMu sync.Mutex
+	participantLimiters   map[string]*ratelimit.Bucket
 }
 
 type TcpListenerDeps struct {
 	Logger     zerolog.Logger
 	Listener   networkListener
 	NewScanner newScanner
+	// Scenario decides the outcome of the payments. Defaults to scenario.Default.
+	Scenario *scenario.Scenario
+	// Journal records the handled requests. Optional.
+	Journal recorder
+	// Clock times the processing delays and the grace period. Defaults to clock.Real.
+	Clock clock.Clock
+	// Metrics are labelled with the bound address of the listener. Optional.
+	Metrics *metrics.Listener
+}
+
+// Options configure the listener. The zero value accepts any number of connections and
+// requests without timeouts.
+type Options struct {
+	// Timeouts close the connections of slow or silent clients. Disabled by default.
+	Timeouts Timeouts
+	// MaxRequestSize is the maximum length of a request line. Defaults to DefaultMaxRequestSize.
+	MaxRequestSize int
+	// OversizedRequests is the policy for the longer requests. Defaults to OversizedReject.
+	OversizedRequests OversizedPolicy
+	// MaxConnections limits the open connections. Zero means no limit.
+	MaxConnections int
+	// ConnectionLimit is the policy for the connections over the maximum. Defaults to
+	// ConnectionsRefuse.
+	ConnectionLimit ConnectionPolicy
+	// RateLimit throttles the requests per connection and per listener. Disabled by default.
+	RateLimit RateLimit
+	// Heartbeat is the interval of the heartbeats sent to idle connections, which must
+	// acknowledge them before the next one. Zero disables them.
+	Heartbeat time.Duration
+	// Participants enables the sessions: the connections must log on as one of them
+	// before sending requests. Optional.
+	Participants *session.Participants
+	// Sequencing numbers the requests and responses of each connection. Disabled by
+	// default.
+	Sequencing Sequencing
 }
 
-func New(port uint16, waitPeriod time.Duration, deps *TcpListenerDeps) (*TcpListener, error) {
-	l, err := deps.Listener.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+func (o *Options) setDefaults() {
+	if o.MaxRequestSize <= 0 {
+		o.MaxRequestSize = DefaultMaxRequestSize
+	}
+	if o.OversizedRequests == "" {
+		o.OversizedRequests = OversizedReject
+	}
+	if o.ConnectionLimit == "" {
+		o.ConnectionLimit = ConnectionsRefuse
+	}
+	o.RateLimit.setDefaults()
+	o.Sequencing.setDefaults()
+}
+
+func (o Options) validate() error {
+	return errors.Join(o.OversizedRequests.validate(), o.ConnectionLimit.validate(), o.RateLimit.validate(), o.Sequencing.validate())
+}
+
+// New listens on the address, e.g. "localhost:8080" or ":8080" for all interfaces.
+func New(address string, waitPeriod time.Duration, options Options, deps *TcpListenerDeps) (*TcpListener, error) {
+	options.setDefaults()
+	if err := options.validate(); err != nil {
+		deps.Logger.Error().Err(err).Msg("Error configuring listener.")
+		return nil, err
+	}
+	l, err := deps.Listener.Listen("tcp", address)
 	if err != nil {
-		deps.Logger.Error().Err(err).Msg("Error listening connection.")
+		deps.Logger.Error().Err(err).Str("address", address).Msg("Error listening connection.")
 		return nil, err
 	}
-	return &TcpListener{
-			listener:         l,
-			deps:             *deps,
-			waitPeriod:       waitPeriod,
-			connections:      make(map[net.Conn]struct{}),
-			shutdownListener: false,
-		},
-		nil
+	listenerDeps := *deps
+	if listenerDeps.Scenario == nil {
+		listenerDeps.Scenario = scenario.Default()
+	}
+	if listenerDeps.Clock == nil {
+		listenerDeps.Clock = clock.Real{}
+	}
+	if listenerDeps.Metrics == nil {
+		listenerDeps.Metrics = metrics.NewListener(metrics.NewRegistry())
+	}
+	listenerDeps.Logger = deps.Logger.With().Str("listener", l.Addr().String()).Logger()
+	ctx, cancel := context.WithCancel(context.Background())
+	listener := &TcpListener{
+		address:             l.Addr().String(),
+		listener:            l,
+		options:             options,
+		deps:                listenerDeps,
+		connections:         make(map[*connection]struct{}),
+		requests:            newRequestRegistry(listenerDeps.Clock),
+		payments:            payment.NewStore(listenerDeps.Clock),
+		shutdownListener:    false,
+		stopping:            make(chan struct{}),
+		ctx:                 ctx,
+		participantLimiters: make(map[string]*ratelimit.Bucket),
+		cancel:              cancel,
+	}
+	if options.MaxConnections > 0 {
+		listener.slots = make(chan struct{}, options.MaxConnections)
+	}
+	listener.limiter = listener.newBucket(options.RateLimit.Global)
+	listener.SetGracePeriod(waitPeriod)
+	listener.SetScenario(listenerDeps.Scenario)
+	return listener, nil
 }
 
 func (l *TcpListener) Start() {
 	l.deps.Logger.Info().Msg("Starting service...")
 	for {
+		if !l.waitForSlot() {
+			break
+		}
 		connection, err := l.listener.Accept()
 		if err != nil {
 			l.mu.Lock()
@@ -87,15 +211,77 @@ func (l *TcpListener) Start() {
 			}
 			continue
 		}
-		l.deps.Logger.Info().Msg("Accepted new connection.")
-		l.storeConnection(connection)
-		go l.handleConnection(connection)
+		if !l.acquireSlot() {
+			go l.refuseConnection(connection)
+			continue
+		}
+		conn := l.storeConnection(connection)
+		l.deps.Logger.Info().Uint64("connection", conn.id).Msg("Accepted new connection.")
+		go l.handleConnection(conn)
 	}
 }
 
+// Stop closes the listener and waits for the in-flight requests to complete, cancelling
+// them when the grace period expires. Concurrent and subsequent calls wait for the first
+// one to finish.
 func (l *TcpListener) Stop() {
+	l.stopOnce.Do(l.stop)
+}
+
+// SetGracePeriod changes the time given to the in-flight requests to complete on Stop.
+func (l *TcpListener) SetGracePeriod(waitPeriod time.Duration) {
+	l.waitPeriod.Store(int64(waitPeriod))
+}
+
+func (l *TcpListener) GracePeriod() time.Duration {
+	return time.Duration(l.waitPeriod.Load())
+}
+
+// SetScenario replaces the scenario used for the requests received from now on.
+func (l *TcpListener) SetScenario(s *scenario.Scenario) {
+	l.scenario.Store(s)
+}
+
+func (l *TcpListener) Scenario() *scenario.Scenario {
+	return l.scenario.Load()
+}
+
+func (l *TcpListener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+func (l *TcpListener) Connections() []ConnectionInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	connections := make([]ConnectionInfo, 0, len(l.connections))
+	for conn := range l.connections {
+		connections = append(connections, ConnectionInfo{
+			ID:            conn.id,
+			Listener:      l.address,
+			RemoteAddr:    conn.RemoteAddr().String(),
+			AcceptedAt:    conn.acceptedAt,
+			Active:        conn.isActive(),
+			Pipelined:     conn.pipelined,
+			ClientSubject: conn.clientSubject,
+			Participant:   conn.participantID(),
+		})
+	}
+	sort.Slice(connections, func(i, j int) bool { return connections[i].ID < connections[j].ID })
+	return connections
+}
+
+func (l *TcpListener) Requests() []RequestInfo {
+	requests := l.requests.list()
+	for i := range requests {
+		requests[i].Listener = l.address
+	}
+	return requests
+}
+
+func (l *TcpListener) stop() {
 	l.mu.Lock()
 	l.shutdownListener = true
+	close(l.stopping)
 	err := l.listener.Close()
 	l.mu.Unlock()
 	if err != nil {
@@ -103,37 +289,41 @@ func (l *TcpListener) Stop() {
 		return
 	}
 
-	done := make(chan struct{})
-	go func() {
-		l.wg.Wait()
-		close(done)
-	}()
+	drained := l.requests.drain()
+	l.closeIdleConnections()
 
 	select {
-	case <-done:
-		l.deps.Logger.Info().Msg("All connections completed gracefully.")
-	case <-time.After(l.waitPeriod):
+	case <-drained:
+		l.deps.Logger.Info().Msg("All requests completed gracefully.")
+	case <-l.deps.Clock.After(l.GracePeriod()):
 		l.deps.Logger.Info().Msg("Grace period finished for active requests. Cancelling pending requests...")
-		l.closeConnections()
+		l.cancel()
+		<-drained
 	}
 }
 
-func (l *TcpListener) storeConnection(conn net.Conn) {
-	l.wg.Add(1)
+func (l *TcpListener) storeConnection(conn net.Conn) *connection {
 	l.mu.Lock()
-	l.connections[conn] = struct{}{}
-	l.mu.Unlock()
+	defer l.mu.Unlock()
+	l.nextConnectionID++
+	c := &connection{Conn: conn, id: l.nextConnectionID, acceptedAt: l.deps.Clock.Now(), limiter: l.newBucket(l.options.RateLimit.PerConnection)}
+	l.connections[c] = struct{}{}
+	l.deps.Metrics.AcceptedConnections.Inc(l.address)
+	l.deps.Metrics.ActiveConnections.Inc(l.address)
+	return c
 }
 
-func (l *TcpListener) closeConnections() {
-	defer l.mu.Unlock()
+// closeIdleConnections closes the connections without a request in flight. Connections
+// with a request in flight are closed by their handler once the response is sent.
+func (l *TcpListener) closeIdleConnections() {
 	l.mu.Lock()
+	defer l.mu.Unlock()
 	for connection := range l.connections {
-		rejected := response.NewRejected("Cancelled")
-		if err := l.sendResponse(connection, rejected.ToString()); err != nil {
-			l.deps.Logger.Error().Err(err).Msg("Error sending cancelled response.")
-			return
+		if connection.isActive() {
+			continue
 		}
+		delete(l.connections, connection)
+		l.deps.Metrics.ActiveConnections.Dec(l.address)
 		l.closeConnection(connection)
 	}
 }
@@ -145,37 +335,171 @@ func (l *TcpListener) closeConnection(connection net.Conn) {
 	}
 }
 
-func (l *TcpListener) sendResponse(connection net.Conn, resp string) (err error) {
+func (l *TcpListener) sendResponse(connection *connection, resp string) (err error) {
 	l.deps.Logger.Debug().Str("response", resp).Msg("Sending response.")
-	_, err = fmt.Fprintf(connection, "%s\n", resp)
-	if err != nil {
-		l.deps.Logger.Error().Err(err).Msg("Error writing response to connection.")
-	}
-	return
+	return l.write(connection, resp+"\n")
 }
 
-func (l *TcpListener) handleConnection(connection net.Conn) {
-	defer l.wg.Done()
+func (l *TcpListener) handleConnection(connection *connection) {
+	defer l.releaseSlot()
 	defer l.deleteAndCloseConnection(connection)
+	defer connection.pending.Wait()
 
-	scanner := l.deps.NewScanner.NewScanner(connection)
+	if l.handshake(connection) != nil {
+		return
+	}
+
+	if l.options.Heartbeat > 0 {
+		stop := make(chan struct{})
+		defer close(stop)
+		go l.superviseConnection(connection, stop)
+	}
+
+	var reader io.Reader = connection
+	var deadlines *deadlineReader
+	if l.options.Timeouts.Idle > 0 || l.options.Timeouts.Read > 0 {
+		deadlines = &deadlineReader{connection: connection, timeouts: l.options.Timeouts}
+		reader = deadlines
+	}
+	scanner := l.deps.NewScanner.NewScanner(reader, l.options.MaxRequestSize)
 	for scanner.Scan() {
+		// the scanner returns the incomplete line read before a timeout as the last one
+		if deadlines != nil && deadlines.timedOut {
+			break
+		}
 		request := scanner.Text()
+		if request == oversizedRequest {
+			if !l.handleOversizedRequest(connection) {
+				return
+			}
+			continue
+		}
+		if l.options.Sequencing.Enabled {
+			var handled, open bool
+			if request, handled, open = l.sequence(connection, request); handled {
+				if !open {
+					return
+				}
+				continue
+			}
+		}
+		if l.acknowledgeHeartbeat(connection, request) {
+			continue
+		}
+		receivedAt := l.deps.Clock.Now()
+		if handled, open := l.handleSession(connection, request, receivedAt); handled {
+			if !open {
+				return
+			}
+			continue
+		}
+		if connection.pipelined {
+			if !l.handlePipelinedRequest(connection, request, receivedAt) {
+				return
+			}
+			continue
+		}
 		l.deps.Logger.Debug().Str("request", request).Msg("Received request.")
-		payment := payment.FromString(request)
-		resp := payment.Process()
-		if err := l.sendResponse(connection, resp.ToString()); err != nil {
+		if request == pipelineRequest {
+			if l.enablePipelining(connection, receivedAt) != nil {
+				return
+			}
+			continue
+		}
+
+		id, ok := l.requests.add(connection, request)
+		if !ok {
+			l.deps.Logger.Debug().Str("request", request).Msg("Discarding request.")
+			return
+		}
+		if err := l.processRequest(connection, id, "", request, receivedAt); err != nil || l.requests.isDraining() {
 			return
 		}
 	}
-	if err := scanner.Err(); err != nil {
+	err := scanner.Err()
+	if reason, ok := timeoutReason(err); ok {
+		l.logClosing(connection, reason)
+	} else if err != nil && !errors.Is(err, net.ErrClosed) {
 		l.deps.Logger.Error().Err(err).Msg("Error reading from connection.")
 	}
 }
 
-func (l *TcpListener) deleteAndCloseConnection(connection net.Conn) {
+// processRequest processes an accepted request and sends its response, tagged with the
+// correlation id in pipelined connections.
+func (l *TcpListener) processRequest(connection *connection, id uint64, correlationID string, request string, receivedAt time.Time) error {
+	defer l.requests.remove(id)
+
+	resp, fault := l.handleRequest(connection, request)
+	line := resp.ToString()
+	if correlationID != "" {
+		line = tag(correlationID, line)
+	}
+
+	var err error
+	if fault != scenario.FaultNone {
+		err = l.injectFault(connection, fault, line)
+	} else {
+		err = l.sendResponse(connection, line)
+	}
+	if correlationID != "" {
+		request = tag(correlationID, request)
+	}
+	respondedAt := l.deps.Clock.Now()
+	if fault != scenario.FaultNone {
+		// the client didn't get the response of the scenario
+		l.deps.Metrics.FaultedRequests.Inc(l.address, string(fault))
+	} else {
+		l.observe(resp, respondedAt.Sub(receivedAt))
+	}
+	l.record(connection, journal.NewEntry(connection.id, request, line, string(fault), receivedAt, respondedAt))
+	return err
+}
+
+// handleRequest returns the response to the request, applying the rate limits to all but
+// ECHO.
+func (l *TcpListener) handleRequest(connection *connection, request string) (response.Response, scenario.Fault) {
+	if request == echoRequest {
+		return l.echo(), scenario.FaultNone
+	}
+	if resp, throttled := l.throttle(connection); throttled {
+		return resp, scenario.FaultNone
+	}
+	return l.payments.Handle(l.ctx, connection.participantID(), request, l.scenarioFor(connection))
+}
+
+func (l *TcpListener) observe(resp response.Response, delay time.Duration) {
+	reason := resp.Reason
+	// the echo timestamps would make a series per echo
+	if resp.Status == echoStatus {
+		reason = ""
+	}
+	l.deps.Metrics.Requests.Inc(l.address, resp.Status, reason)
+	l.deps.Metrics.ProcessingDelay.Observe(delay.Seconds(), l.address)
+	if l.ctx.Err() != nil && resp == response.NewRejected("Cancelled") {
+		l.deps.Metrics.CancelledOnShutdown.Inc(l.address)
+	}
+}
+
+func (l *TcpListener) record(connection *connection, entry journal.Entry) {
+	if l.deps.Journal == nil {
+		return
+	}
+	entry.Listener = l.address
+	if participant := connection.participant.Load(); participant != nil {
+		entry.Participant = participant.ID
+	}
+	if err := l.deps.Journal.Record(entry); err != nil {
+		l.deps.Logger.Error().Err(err).Msg("Error recording request in journal.")
+	}
+}
+
+func (l *TcpListener) deleteAndCloseConnection(connection *connection) {
 	l.mu.Lock()
+	_, ok := l.connections[connection]
 	delete(l.connections, connection)
 	l.mu.Unlock()
-	l.closeConnection(connection)
+	if ok {
+		l.deps.Metrics.ActiveConnections.Dec(l.address)
+		l.closeConnection(connection)
+	}
 }
@@ -4,16 +4,22 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/clock"
+	"github.com/form3tech-oss/interview-simulator/internal/metrics"
 	"github.com/form3tech-oss/interview-simulator/internal/mocks"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
 	"github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
 	"github.com/rs/zerolog"
-	"math/rand"
+	"io"
 	"net"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -22,21 +28,89 @@ const (
 )
 
 type logSink struct {
+	mu   sync.Mutex
 	logs []string
 }
 
 func (l *logSink) Write(p []byte) (n int, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.logs = append(l.logs, string(p))
 	return len(p), nil
 }
 
 func (l *logSink) Last() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	return l.logs[(len(l.logs) - 1)]
 }
 
+func (l *logSink) All() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.logs, "")
+}
+
+// startListener starts a listener on a random port of localhost, stopped when the test
+// completes. The network listener and scanner default to the real ones, and the zero
+// logger discards the logs.
+func startListener(t *testing.T, options tcp_listener.Options, deps tcp_listener.TcpListenerDeps) *tcp_listener.TcpListener {
+	t.Helper()
+	if deps.Listener == nil {
+		deps.Listener = tcp_listener.NetListener{}
+	}
+	if deps.NewScanner == nil {
+		deps.NewScanner = tcp_listener.BufioScanner{}
+	}
+	listener, err := tcp_listener.New("localhost:0", 0, options, &deps)
+	require.NoError(t, err)
+	go listener.Start()
+	t.Cleanup(listener.Stop)
+	return listener
+}
+
+// testConn is a client connection, closed when the test completes.
+type testConn struct {
+	net.Conn
+	t      *testing.T
+	reader *bufio.Reader
+}
+
+func dial(t *testing.T, address string) *testConn {
+	t.Helper()
+	conn, err := net.Dial("tcp", address)
+	require.NoError(t, err, "Failed to connect to server")
+	t.Cleanup(func() { conn.Close() })
+	return &testConn{Conn: conn, t: t, reader: bufio.NewReader(conn)}
+}
+
+// write sends the request without waiting for the response.
+func (c *testConn) write(request string) {
+	c.t.Helper()
+	_, err := fmt.Fprintf(c, "%s\n", request)
+	assert.NoError(c.t, err, "Failed to send request")
+}
+
+// read returns the next line received, without the line break.
+func (c *testConn) read() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	return strings.TrimSpace(line), err
+}
+
+// send sends the request and returns the response.
+func (c *testConn) send(request string) string {
+	c.t.Helper()
+	c.write(request)
+	response, err := c.read()
+	assert.NoError(c.t, err, "Failed to read response")
+	return response
+}
+
 type NetListenTestSuite struct {
 	suite.Suite
 	listener *tcp_listener.TcpListener
+	clock    *clock.Fake
+	metrics  *metrics.Listener
 	port     uint16
 }
 
@@ -47,82 +121,105 @@ func TestNetListenSuite(t *testing.T) {
 
 func (suite *NetListenTestSuite) SetupTest() {
 	logger := zerolog.New(os.Stdout).With().Timestamp().Logger()
-	port := rndPort()
-	listener, err := tcp_listener.New(port, WAIT_PERIOD, &tcp_listener.TcpListenerDeps{Logger: logger, Listener: tcp_listener.NetListener{}, NewScanner: tcp_listener.BufioScanner{}})
+	suite.clock = clock.NewFake(time.Now())
+	suite.metrics = metrics.NewListener(metrics.NewRegistry())
+	listener, err := tcp_listener.New("localhost:0", WAIT_PERIOD, tcp_listener.Options{}, &tcp_listener.TcpListenerDeps{Logger: logger, Listener: tcp_listener.NetListener{}, NewScanner: tcp_listener.BufioScanner{}, Clock: suite.clock, Metrics: suite.metrics})
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 	suite.listener = listener
-	suite.port = port
+	suite.port = uint16(listener.Addr().(*net.TCPAddr).Port)
 
 	go suite.listener.Start()
-
-	// wait of the server to be ready
-	time.Sleep(time.Second)
 }
 
 func (suite *NetListenTestSuite) TearDownTest() {
+	// cancel the requests left in flight without waiting for the grace period
+	suite.listener.SetGracePeriod(0)
+	suite.clock.Advance(WAIT_PERIOD)
 	suite.listener.Stop()
 }
 
+// elapse waits for the timers to be set and advances the clock by d, checking none of
+// them fires a millisecond earlier.
+func (suite *NetListenTestSuite) elapse(timers int, d time.Duration) {
+	suite.clock.BlockUntil(timers)
+	suite.clock.Advance(d - time.Millisecond)
+	suite.Equal(timers, suite.clock.Waiters(), "Timer fired earlier than expected")
+	suite.clock.Advance(time.Millisecond)
+}
+
+// waitForConnections waits for the listener to accept the dialled connections.
+func (suite *NetListenTestSuite) waitForConnections(n int) {
+	suite.Eventually(func() bool {
+		return len(suite.listener.Connections()) == n
+	}, time.Second, 10*time.Millisecond, "Connections were not accepted")
+}
+
 func (suite *NetListenTestSuite) TestSchemeSimulator() {
 	tests := []struct {
 		name           string
 		input          string
 		expectedOutput string
-		minDuration    time.Duration
-		maxDuration    time.Duration
+		delay          time.Duration
 	}{
 		{
 			name:           "Valid Request",
 			input:          "PAYMENT|10",
 			expectedOutput: "RESPONSE|ACCEPTED|Transaction processed",
-			maxDuration:    50 * time.Millisecond,
 		},
 		{
 			name:           "Valid Request with Delay",
 			input:          "PAYMENT|101",
 			expectedOutput: "RESPONSE|ACCEPTED|Transaction processed",
-			minDuration:    101 * time.Millisecond,
-			maxDuration:    151 * time.Millisecond,
+			delay:          101 * time.Millisecond,
 		},
 		{
 			name:           "Invalid Amount with negative number",
 			input:          "PAYMENT|-101",
 			expectedOutput: "RESPONSE|REJECTED|Invalid amount",
-			maxDuration:    10 * time.Millisecond,
 		},
 		{
 			name:           "Invalid Amount with decimal numbers",
 			input:          "PAYMENT|101.123",
 			expectedOutput: "RESPONSE|REJECTED|Invalid amount",
-			maxDuration:    10 * time.Millisecond,
 		},
 		{
 			name:           "Empty Amount",
 			input:          "PAYMENT|",
 			expectedOutput: "RESPONSE|REJECTED|Invalid amount",
-			maxDuration:    10 * time.Millisecond,
 		},
 		{
 			name:           "Invalid Request Format",
 			input:          "INVALID|100",
 			expectedOutput: "RESPONSE|REJECTED|Invalid request",
-			maxDuration:    10 * time.Millisecond,
 		},
 		{
 			name:           "Invalid Request Format with extra field",
-			input:          "PAYMENT|10|HELLO",
+			input:          "PAYMENT|abc|10|HELLO",
 			expectedOutput: "RESPONSE|REJECTED|Invalid request",
-			maxDuration:    10 * time.Millisecond,
+		},
+		{
+			name:           "Valid Request with id",
+			input:          "PAYMENT|abc|10",
+			expectedOutput: "RESPONSE|ACCEPTED|Transaction processed",
+		},
+		{
+			name:           "Empty id",
+			input:          "PAYMENT||10",
+			expectedOutput: "RESPONSE|REJECTED|Invalid request",
+		},
+		{
+			name:           "Invalid Amount with id",
+			input:          "PAYMENT|def|HELLO",
+			expectedOutput: "RESPONSE|REJECTED|Invalid amount",
 		},
 		{
 			name:           "Large Amount",
 			input:          "PAYMENT|20000",
 			expectedOutput: "RESPONSE|ACCEPTED|Transaction processed",
-			minDuration:    10 * time.Second,
-			maxDuration:    10*time.Second + 50*time.Millisecond,
+			delay:          10 * time.Second,
 		},
 	}
 
@@ -135,23 +232,16 @@ func (suite *NetListenTestSuite) TestSchemeSimulator() {
 			_, err = fmt.Fprintf(conn, tt.input+"\n")
 			suite.NoError(err, "Failed to send request")
 
-			start := time.Now()
+			if tt.delay > 0 {
+				suite.elapse(1, tt.delay)
+			}
 
 			response, err := bufio.NewReader(conn).ReadString('\n')
 			suite.NoError(err, "Failed to read response")
-			duration := time.Since(start)
 
 			response = strings.TrimSpace(response)
 
 			suite.Equal(tt.expectedOutput, response, "Unexpected response")
-
-			if tt.minDuration > 0 {
-				suite.GreaterOrEqual(duration, tt.minDuration, "Response time was shorter than expected")
-			}
-
-			if tt.maxDuration > 0 {
-				suite.LessOrEqual(duration, tt.maxDuration, "Response time was longer than expected")
-			}
 		})
 	}
 }
@@ -171,25 +261,260 @@ func (suite *NetListenTestSuite) Test_TwoRequestsInOneConnection() {
 	_, err = fmt.Fprintf(conn, msg2+"\n")
 	suite.NoError(err, "Failed to send request 2")
 
-	start := time.Now()
+	suite.elapse(1, 10*time.Second)
+
+	reader := bufio.NewReader(conn)
 
-	response1, err := bufio.NewReader(conn).ReadString('\n')
+	response1, err := reader.ReadString('\n')
 	suite.NoError(err, "Failed to read response")
 	response1 = strings.TrimSpace(response1)
 
-	firstResponseTime := time.Now()
-
-	response2, err := bufio.NewReader(conn).ReadString('\n')
+	response2, err := reader.ReadString('\n')
 	suite.NoError(err, "Failed to read response")
 	response2 = strings.TrimSpace(response2)
 
-	secondResponseTime := time.Now()
-
 	suite.Equal(expectedResponse1, response1, "Unexpected response")
 	suite.Equal(expectedResponse2, response2, "Unexpected response")
+}
 
-	suite.LessOrEqual(firstResponseTime.Sub(start), 10*time.Second+50*time.Millisecond, "Response time was longer than expected")
-	suite.LessOrEqual(secondResponseTime.Sub(firstResponseTime), 50*time.Millisecond, "Response time was longer than expected")
+func (suite *NetListenTestSuite) Test_ResubmittedPayments() {
+	tests := []struct {
+		name           string
+		input          string
+		expectedOutput string
+		delay          time.Duration
+	}{
+		{
+			name:           "Original payment",
+			input:          "PAYMENT|p-1|200",
+			expectedOutput: "RESPONSE|ACCEPTED|Transaction processed",
+			delay:          200 * time.Millisecond,
+		},
+		{
+			name:           "Resubmission with the same amount",
+			input:          "PAYMENT|p-1|200",
+			expectedOutput: "RESPONSE|ACCEPTED|Transaction processed",
+		},
+		{
+			name:           "Resubmission with a different amount",
+			input:          "PAYMENT|p-1|300",
+			expectedOutput: "RESPONSE|REJECTED|Duplicate payment",
+		},
+		{
+			name:           "Different id",
+			input:          "PAYMENT|p-2|300",
+			expectedOutput: "RESPONSE|ACCEPTED|Transaction processed",
+			delay:          300 * time.Millisecond,
+		},
+	}
+
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			_, err = fmt.Fprintf(conn, tt.input+"\n")
+			suite.NoError(err, "Failed to send request")
+
+			if tt.delay > 0 {
+				suite.elapse(1, tt.delay)
+			}
+
+			response, err := reader.ReadString('\n')
+			suite.NoError(err, "Failed to read response")
+
+			suite.Equal(tt.expectedOutput, strings.TrimSpace(response), "Unexpected response")
+		})
+	}
+}
+
+func (suite *NetListenTestSuite) Test_RefundsAndReversals() {
+	tests := []struct {
+		name           string
+		input          string
+		expectedOutput string
+	}{
+		{"Original payment", "PAYMENT|p-1|50", "RESPONSE|ACCEPTED|Transaction processed"},
+		{"Other payment", "PAYMENT|p-2|80", "RESPONSE|ACCEPTED|Transaction processed"},
+		{"Rejected payment", "PAYMENT|p-3|abc", "RESPONSE|REJECTED|Invalid amount"},
+		{"Partial refund", "REFUND|p-1|20", "RESPONSE|ACCEPTED|Refund processed"},
+		{"Refund larger than the rest", "REFUND|p-1|31", "RESPONSE|REJECTED|Refund exceeds payment"},
+		{"Refund of the rest", "REFUND|p-1|30", "RESPONSE|ACCEPTED|Refund processed"},
+		{"Refund of a fully refunded payment", "REFUND|p-1|1", "RESPONSE|REJECTED|Refund exceeds payment"},
+		{"Refund of an unknown payment", "REFUND|p-9|10", "RESPONSE|REJECTED|Unknown payment"},
+		{"Refund of a rejected payment", "REFUND|p-3|10", "RESPONSE|REJECTED|Unknown payment"},
+		{"Refund with an invalid amount", "REFUND|p-2|0", "RESPONSE|REJECTED|Invalid amount"},
+		{"Refund without amount", "REFUND|p-2", "RESPONSE|REJECTED|Invalid request"},
+		{"Reversal of a refunded payment", "REVERSAL|p-1", "RESPONSE|REJECTED|Payment refunded"},
+		{"Reversal", "REVERSAL|p-2", "RESPONSE|ACCEPTED|Reversal processed"},
+		{"Second reversal", "REVERSAL|p-2", "RESPONSE|REJECTED|Payment already reversed"},
+		{"Refund of a reversed payment", "REFUND|p-2|10", "RESPONSE|REJECTED|Payment reversed"},
+		{"Reversal of an unknown payment", "REVERSAL|p-9", "RESPONSE|REJECTED|Unknown payment"},
+		{"Reversal without id", "REVERSAL|", "RESPONSE|REJECTED|Invalid request"},
+	}
+
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			_, err = fmt.Fprintf(conn, tt.input+"\n")
+			suite.NoError(err, "Failed to send request")
+
+			response, err := reader.ReadString('\n')
+			suite.NoError(err, "Failed to read response")
+
+			suite.Equal(tt.expectedOutput, strings.TrimSpace(response), "Unexpected response")
+		})
+	}
+}
+
+func (suite *NetListenTestSuite) Test_PaymentStatus() {
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	enquiry, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer enquiry.Close()
+	enquiryReader := bufio.NewReader(enquiry)
+	status := func(id string) string {
+		_, err := fmt.Fprintf(enquiry, "STATUS|%s\n", id)
+		suite.NoError(err, "Failed to send status request")
+		response, err := enquiryReader.ReadString('\n')
+		suite.NoError(err, "Failed to read status response")
+		return strings.TrimSpace(response)
+	}
+
+	_, err = fmt.Fprintf(conn, "PAYMENT|p-1|500\n")
+	suite.NoError(err, "Failed to send request")
+	suite.clock.BlockUntil(1)
+	suite.Equal("RESPONSE|PENDING|Payment pending", status("p-1"))
+	suite.Equal("RESPONSE|UNKNOWN|Unknown payment", status("p-9"))
+
+	suite.elapse(1, 500*time.Millisecond)
+	_, err = reader.ReadString('\n')
+	suite.NoError(err, "Failed to read response")
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", status("p-1"))
+
+	rules, err := scenario.Parse([]byte("rules: [{amounts: [13], status: REJECTED, reason: Unlucky}, {amounts: [14], fault: drop}]"))
+	suite.Require().NoError(err)
+	suite.listener.SetScenario(rules)
+	_, err = fmt.Fprintf(conn, "PAYMENT|p-2|13\n")
+	suite.NoError(err, "Failed to send request")
+	_, err = reader.ReadString('\n')
+	suite.NoError(err, "Failed to read response")
+	suite.Equal("RESPONSE|REJECTED|Unlucky", status("p-2"))
+
+	_, err = fmt.Fprintf(conn, "PAYMENT|p-3|14\n")
+	suite.NoError(err, "Failed to send request")
+	_, err = reader.ReadString('\n')
+	suite.Error(err, "Connection should be dropped")
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", status("p-3"), "Payment should be processed despite the dropped connection")
+
+	suite.Equal("RESPONSE|REJECTED|Invalid request", status(""))
+}
+
+func (suite *NetListenTestSuite) Test_ConcurrentResubmissionWaitsForOriginalPayment() {
+	conn1, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer conn1.Close()
+
+	conn2, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer conn2.Close()
+
+	_, err = fmt.Fprintf(conn1, "PAYMENT|p-3|500\n")
+	suite.NoError(err, "Failed to send request 1")
+
+	// wait for the original request to be accepted
+	suite.clock.BlockUntil(1)
+
+	_, err = fmt.Fprintf(conn2, "PAYMENT|p-3|500\n")
+	suite.NoError(err, "Failed to send request 2")
+
+	suite.Eventually(func() bool {
+		return len(suite.listener.Requests()) == 2
+	}, time.Second, 10*time.Millisecond, "Resubmission was not accepted")
+	suite.elapse(1, 500*time.Millisecond)
+
+	response, err := bufio.NewReader(conn2).ReadString('\n')
+	suite.NoError(err, "Failed to read response")
+
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", strings.TrimSpace(response), "Unexpected response")
+}
+
+func (suite *NetListenTestSuite) Test_PipelinedRequestsAreProcessedConcurrently() {
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	_, err = fmt.Fprintf(conn, "PIPELINE\n")
+	suite.NoError(err, "Failed to send pipeline request")
+	response, err := reader.ReadString('\n')
+	suite.NoError(err, "Failed to read response")
+	suite.Equal("RESPONSE|ACCEPTED|Pipelining enabled", strings.TrimSpace(response))
+
+	for _, request := range []string{"a|PAYMENT|500", "b|PAYMENT|200", "c|PAYMENT|abc", "PAYMENT"} {
+		_, err = fmt.Fprintf(conn, request+"\n")
+		suite.NoError(err, "Failed to send request")
+	}
+
+	var responses []string
+	readResponse := func() {
+		response, err := reader.ReadString('\n')
+		suite.NoError(err, "Failed to read response")
+		responses = append(responses, strings.TrimSpace(response))
+	}
+	readResponse()
+	readResponse()
+
+	// both delayed requests are waiting at the same time
+	suite.elapse(2, 200*time.Millisecond)
+	readResponse()
+	suite.elapse(1, 300*time.Millisecond)
+	readResponse()
+
+	suite.ElementsMatch([]string{"c|RESPONSE|REJECTED|Invalid amount", "RESPONSE|REJECTED|Invalid request"}, responses[:2])
+	suite.Equal("b|RESPONSE|ACCEPTED|Transaction processed", responses[2])
+	suite.Equal("a|RESPONSE|ACCEPTED|Transaction processed", responses[3])
+}
+
+func (suite *NetListenTestSuite) Test_StoppingServiceCompletesPipelinedRequests() {
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	_, err = fmt.Fprintf(conn, "PIPELINE\na|PAYMENT|300\nb|PAYMENT|600\n")
+	suite.NoError(err, "Failed to send requests")
+	response, err := reader.ReadString('\n')
+	suite.NoError(err, "Failed to read response")
+	suite.Equal("RESPONSE|ACCEPTED|Pipelining enabled", strings.TrimSpace(response))
+
+	// wait for the requests to be accepted
+	suite.clock.BlockUntil(2)
+
+	go suite.listener.Stop()
+
+	// wait for the grace period to start
+	suite.clock.BlockUntil(3)
+	suite.clock.Advance(300 * time.Millisecond)
+	response1, err := reader.ReadString('\n')
+	suite.NoError(err, "Failed to read response")
+	suite.clock.Advance(300 * time.Millisecond)
+	response2, err := reader.ReadString('\n')
+	suite.NoError(err, "Failed to read response")
+	_, err = reader.ReadString('\n')
+
+	suite.Equal("a|RESPONSE|ACCEPTED|Transaction processed", strings.TrimSpace(response1))
+	suite.Equal("b|RESPONSE|ACCEPTED|Transaction processed", strings.TrimSpace(response2))
+	suite.ErrorIs(err, io.EOF, "Connection should be closed after the last request")
 }
 
 func (suite *NetListenTestSuite) Test_TwoRequestsInTwoConnections() {
@@ -210,8 +535,6 @@ func (suite *NetListenTestSuite) Test_TwoRequestsInTwoConnections() {
 	_, err = fmt.Fprintf(conn2, msg2+"\n")
 	suite.NoError(err, "Failed to send request 2")
 
-	start := time.Now()
-
 	response1, err := bufio.NewReader(conn1).ReadString('\n')
 	suite.NoError(err, "Failed to read response")
 	response1 = strings.TrimSpace(response1)
@@ -220,12 +543,8 @@ func (suite *NetListenTestSuite) Test_TwoRequestsInTwoConnections() {
 	suite.NoError(err, "Failed to read response")
 	response2 = strings.TrimSpace(response2)
 
-	duration := time.Since(start)
-
 	suite.Equal(expectedResponse, response1, "Unexpected response")
 	suite.Equal(expectedResponse, response2, "Unexpected response")
-
-	suite.LessOrEqual(duration, 50*time.Millisecond, "Response time was longer than expected")
 }
 
 func (suite *NetListenTestSuite) Test_CancelRequestDueToGracePeriodExpiration() {
@@ -239,19 +558,54 @@ func (suite *NetListenTestSuite) Test_CancelRequestDueToGracePeriodExpiration()
 	_, err = fmt.Fprintf(conn, msg1+"\n")
 	suite.NoError(err, "Failed to send request 1")
 
+	// wait for the request to be accepted
+	suite.clock.BlockUntil(1)
+
 	go suite.listener.Stop()
 
-	start := time.Now()
+	suite.elapse(2, WAIT_PERIOD)
 
 	response, err := bufio.NewReader(conn).ReadString('\n')
 	suite.NoError(err, "Failed to read response")
 	response = strings.TrimSpace(response)
 
-	duration := time.Since(start)
-
 	suite.Equal(expectedResponse, response, "Unexpected response")
+}
+
+func (suite *NetListenTestSuite) Test_GracePeriodExpirationClosesIdleConnectionsWithoutResponse() {
+	msg1 := "PAYMENT|50000"
+	expectedResponse := "RESPONSE|REJECTED|Cancelled"
+
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer conn.Close()
+
+	idleConn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer idleConn.Close()
+
+	_, err = fmt.Fprintf(conn, msg1+"\n")
+	suite.NoError(err, "Failed to send request 1")
+
+	// wait for the request and the idle connection to be accepted
+	suite.clock.BlockUntil(1)
+	suite.waitForConnections(2)
+
+	go suite.listener.Stop()
+
+	suite.elapse(2, WAIT_PERIOD)
 
-	suite.LessOrEqual(duration, WAIT_PERIOD+50*time.Millisecond, "Response time was longer than expected")
+	reader := bufio.NewReader(conn)
+	response, err := reader.ReadString('\n')
+	suite.NoError(err, "Failed to read response")
+	suite.Equal(expectedResponse, strings.TrimSpace(response), "Unexpected response")
+
+	_, err = reader.ReadString('\n')
+	suite.ErrorIs(err, io.EOF, "Only one response should be sent")
+
+	idleResponse, err := bufio.NewReader(idleConn).ReadString('\n')
+	suite.ErrorIs(err, io.EOF, "Idle connection should be closed")
+	suite.Empty(idleResponse, "Idle connection should not receive a response")
 }
 
 func (suite *NetListenTestSuite) Test_StoppingServiceStopsNewConnections() {
@@ -264,50 +618,193 @@ func (suite *NetListenTestSuite) Test_StoppingServiceStopsNewConnections() {
 	_, err = fmt.Fprintf(conn, msg1+"\n")
 	suite.NoError(err, "Failed to send request 1")
 
+	// wait for the request to be accepted
+	suite.clock.BlockUntil(1)
+
 	go suite.listener.Stop()
 
-	time.Sleep(1 * time.Second)
+	// the grace period starts once the listener is closed
+	suite.clock.BlockUntil(2)
 
 	conn2, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
 	suite.Error(err, "Failed to connect to server")
 	suite.Nil(conn2, "Connection should be nil")
 }
 
-func (suite *NetListenTestSuite) Test_StoppingServiceKeepsReceivingRequests() {
+func (suite *NetListenTestSuite) Test_StoppingServiceClosesIdleConnections() {
 	msg1 := "PAYMENT|50000"
-	msg2 := "PAYMENT|50"
-	expectedResponse := "RESPONSE|ACCEPTED|Transaction processed"
 
 	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
 	suite.NoError(err, "Failed to connect to server")
 	defer conn.Close()
 
-	conn2, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	idleConn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
 	suite.NoError(err, "Failed to connect to server")
-	defer conn2.Close()
+	defer idleConn.Close()
 
 	_, err = fmt.Fprintf(conn, msg1+"\n")
 	suite.NoError(err, "Failed to send request 1")
 
+	// wait for the request and the idle connection to be accepted
+	suite.clock.BlockUntil(1)
+	suite.waitForConnections(2)
+
 	go suite.listener.Stop()
 
-	time.Sleep(1 * time.Second)
+	// the clock is not advanced, so the idle connection must be closed within the grace period
+	err = idleConn.SetReadDeadline(time.Now().Add(time.Second))
+	suite.NoError(err, "Failed to set read deadline")
+	_, err = bufio.NewReader(idleConn).ReadString('\n')
+	suite.ErrorIs(err, io.EOF, "Idle connection should be closed")
+}
 
-	_, err = fmt.Fprintf(conn2, msg2+"\n")
+func (suite *NetListenTestSuite) Test_StoppingServiceFinishesWhenLastRequestCompletes() {
+	msg1 := "PAYMENT|1000"
+	expectedResponse := "RESPONSE|ACCEPTED|Transaction processed"
+
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer conn.Close()
+
+	idleConn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer idleConn.Close()
+
+	_, err = fmt.Fprintf(conn, msg1+"\n")
 	suite.NoError(err, "Failed to send request 1")
 
-	response, err := bufio.NewReader(conn2).ReadString('\n')
+	// wait for the request and the idle connection to be accepted
+	suite.clock.BlockUntil(1)
+	suite.waitForConnections(2)
+
+	stopped := make(chan struct{})
+	go func() {
+		suite.listener.Stop()
+		close(stopped)
+	}()
+
+	suite.elapse(2, time.Second)
+
+	response, err := bufio.NewReader(conn).ReadString('\n')
 	suite.NoError(err, "Failed to read response")
-	response = strings.TrimSpace(response)
-	suite.Equal(expectedResponse, response, "Unexpected response")
+	suite.Equal(expectedResponse, strings.TrimSpace(response), "Unexpected response")
 
-	_, err = fmt.Fprintf(conn2, msg2+"\n")
-	suite.NoError(err, "Failed to send request 1")
+	// the grace period never expires, so the service stops because the request completed
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		suite.Fail("Service did not stop when the last request completed")
+	}
+}
 
-	response2, err := bufio.NewReader(conn2).ReadString('\n')
+func (suite *NetListenTestSuite) Test_ListsConnectionsAndInFlightRequests() {
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer conn.Close()
+
+	idleConn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer idleConn.Close()
+
+	_, err = fmt.Fprintf(conn, "PAYMENT|500\n")
+	suite.NoError(err, "Failed to send request")
+
+	// wait for the request and the idle connection to be accepted
+	suite.clock.BlockUntil(1)
+	suite.waitForConnections(2)
+
+	connections := suite.listener.Connections()
+	requests := suite.listener.Requests()
+
+	suite.Len(connections, 2)
+	suite.Equal(conn.LocalAddr().String(), connections[0].RemoteAddr)
+	suite.True(connections[0].Active)
+	suite.False(connections[1].Active)
+	suite.Len(requests, 1)
+	suite.Equal("PAYMENT|500", requests[0].Request)
+	suite.Equal(connections[0].ID, requests[0].ConnectionID)
+	suite.Equal(suite.clock.Now(), requests[0].StartedAt)
+}
+
+func (suite *NetListenTestSuite) Test_ScenarioCanBeReplacedAtRuntime() {
+	rules, err := scenario.Parse([]byte(`rules: [{status: REJECTED, reason: Scheme closed}]`))
+	suite.Require().NoError(err)
+
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	_, err = fmt.Fprintf(conn, "PAYMENT|10\n")
+	suite.NoError(err, "Failed to send request")
+	response, err := reader.ReadString('\n')
 	suite.NoError(err, "Failed to read response")
-	response2 = strings.TrimSpace(response2)
-	suite.Equal(expectedResponse, response2, "Unexpected response")
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", strings.TrimSpace(response))
+
+	suite.listener.SetScenario(rules)
+
+	_, err = fmt.Fprintf(conn, "PAYMENT|10\n")
+	suite.NoError(err, "Failed to send request")
+	response, err = reader.ReadString('\n')
+	suite.NoError(err, "Failed to read response")
+	suite.Equal("RESPONSE|REJECTED|Scheme closed", strings.TrimSpace(response))
+}
+
+func (suite *NetListenTestSuite) Test_GracePeriodCanBeChanged() {
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer conn.Close()
+
+	_, err = fmt.Fprintf(conn, "PAYMENT|50000\n")
+	suite.NoError(err, "Failed to send request")
+
+	// wait for the request to be accepted
+	suite.clock.BlockUntil(1)
+
+	suite.listener.SetGracePeriod(500 * time.Millisecond)
+	go suite.listener.Stop()
+
+	suite.elapse(2, 500*time.Millisecond)
+
+	response, err := bufio.NewReader(conn).ReadString('\n')
+	suite.NoError(err, "Failed to read response")
+
+	suite.Equal("RESPONSE|REJECTED|Cancelled", strings.TrimSpace(response))
+}
+
+func (suite *NetListenTestSuite) Test_Metrics() {
+	listener := suite.listener.Addr().String()
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", suite.port))
+	suite.NoError(err, "Failed to connect to server")
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	_, err = fmt.Fprintf(conn, "PAYMENT|10\nPAYMENT|abc\nPAYMENT|50000\n")
+	suite.NoError(err, "Failed to send requests")
+	for range 2 {
+		_, err = reader.ReadString('\n')
+		suite.NoError(err, "Failed to read response")
+	}
+
+	suite.clock.BlockUntil(1)
+	suite.Equal(1.0, suite.metrics.AcceptedConnections.Value(listener))
+	suite.Equal(1.0, suite.metrics.ActiveConnections.Value(listener))
+
+	go suite.listener.Stop()
+	suite.elapse(2, WAIT_PERIOD)
+	_, err = reader.ReadString('\n')
+	suite.NoError(err, "Failed to read response")
+	_, err = reader.ReadString('\n')
+	suite.ErrorIs(err, io.EOF)
+
+	suite.Eventually(func() bool {
+		return suite.metrics.ActiveConnections.Value(listener) == 0
+	}, time.Second, 10*time.Millisecond, "Connection was not closed")
+	suite.Equal(1.0, suite.metrics.Requests.Value(listener, "ACCEPTED", "Transaction processed"))
+	suite.Equal(1.0, suite.metrics.Requests.Value(listener, "REJECTED", "Invalid amount"))
+	suite.Equal(1.0, suite.metrics.Requests.Value(listener, "REJECTED", "Cancelled"))
+	suite.Equal(1.0, suite.metrics.CancelledOnShutdown.Value(listener))
+	suite.Equal(uint64(3), suite.metrics.ProcessingDelay.Count(listener))
 }
 
 type TcpListenerTestSuite struct {
@@ -320,6 +817,9 @@ func TestTcpListenerSuite(t *testing.T) {
 	suite.Run(t, mainTestSuite)
 }
 
+// mockAddr is the address of the mock listeners.
+var mockAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}
+
 func (suite *TcpListenerTestSuite) Test_FailingListener() {
 	logs := &logSink{}
 	logger := zerolog.New(logs).With().Timestamp().Logger()
@@ -329,7 +829,7 @@ func (suite *TcpListenerTestSuite) Test_FailingListener() {
 	nl := mocks.MockNetListener{}
 	nl.On("Listen").Return(l, expectedErr).Once()
 
-	_, err := tcp_listener.New(8080, WAIT_PERIOD, &tcp_listener.TcpListenerDeps{Logger: logger, Listener: &nl, NewScanner: tcp_listener.BufioScanner{}})
+	_, err := tcp_listener.New("localhost:8080", WAIT_PERIOD, tcp_listener.Options{}, &tcp_listener.TcpListenerDeps{Logger: logger, Listener: &nl, NewScanner: tcp_listener.BufioScanner{}})
 
 	suite.Equal(expectedErr, err)
 	nl.AssertExpectations(suite.T())
@@ -342,10 +842,11 @@ func (suite *TcpListenerTestSuite) Test_FailingAcceptShouldRetry() {
 	l := mocks.NewMockListener()
 	l.On("Accept").Return(new(net.TCPConn), errors.New("test error")).Times(2)
 
+	l.On("Addr").Return(mockAddr)
 	nl := mocks.MockNetListener{}
 	nl.On("Listen").Return(l, nil).Once()
 
-	listener, err := tcp_listener.New(8080, WAIT_PERIOD, &tcp_listener.TcpListenerDeps{Logger: logger, Listener: &nl, NewScanner: tcp_listener.BufioScanner{}})
+	listener, err := tcp_listener.New("localhost:8080", WAIT_PERIOD, tcp_listener.Options{}, &tcp_listener.TcpListenerDeps{Logger: logger, Listener: &nl, NewScanner: tcp_listener.BufioScanner{}})
 	go listener.Start()
 	time.Sleep(1 * time.Second)
 
@@ -366,6 +867,7 @@ func (suite *TcpListenerTestSuite) Test_ScannerWithClosedConnectionShouldSilentl
 
 	l := mocks.NewMockListener()
 	l.On("Accept").Return(c, nil)
+	l.On("Addr").Return(mockAddr)
 	nl := mocks.MockNetListener{}
 	nl.On("Listen").Return(l, nil)
 	s := mocks.MockBufioScanner{}
@@ -374,7 +876,7 @@ func (suite *TcpListenerTestSuite) Test_ScannerWithClosedConnectionShouldSilentl
 	s.On("Err").Return(errors.New("test error"))
 	newScanner := mocks.NewMockNewScanner(&s)
 
-	listener, err := tcp_listener.New(8080, WAIT_PERIOD, &tcp_listener.TcpListenerDeps{Logger: logger, Listener: &nl, NewScanner: newScanner})
+	listener, err := tcp_listener.New("localhost:8080", WAIT_PERIOD, tcp_listener.Options{}, &tcp_listener.TcpListenerDeps{Logger: logger, Listener: &nl, NewScanner: newScanner})
 	go listener.Start()
 
 	time.Sleep(1 * time.Second)
@@ -396,6 +898,7 @@ func (suite *TcpListenerTestSuite) Test_CancelledRequestFailingToBeSentShouldSil
 	l := mocks.NewMockListener()
 	l.On("Accept").Return(c, nil)
 	l.On("Close").Return(nil)
+	l.On("Addr").Return(mockAddr)
 	nl := mocks.MockNetListener{}
 	nl.On("Listen").Return(l, nil)
 
@@ -404,17 +907,21 @@ func (suite *TcpListenerTestSuite) Test_CancelledRequestFailingToBeSentShouldSil
 	s.On("Text").Return(msg)
 	s.On("Err").Return(nil)
 	newScanner := mocks.NewMockNewScanner(&s)
+	clk := clock.NewFake(time.Now())
 
-	listener, err := tcp_listener.New(8080, WAIT_PERIOD, &tcp_listener.TcpListenerDeps{Logger: logger, Listener: &nl, NewScanner: newScanner})
+	listener, err := tcp_listener.New("localhost:8080", WAIT_PERIOD, tcp_listener.Options{}, &tcp_listener.TcpListenerDeps{Logger: logger, Listener: &nl, NewScanner: newScanner, Clock: clk})
 	go listener.Start()
 
-	time.Sleep(1 * time.Second)
+	// wait for the request to be accepted
+	clk.BlockUntil(1)
 
+	// cancel the request straight away
+	listener.SetGracePeriod(0)
 	listener.Stop()
 
 	suite.NotNil(listener)
 	suite.Nil(err)
-	suite.Contains(logs.Last(), "Error sending cancelled response.")
+	suite.Contains(logs.All(), "Error writing response to connection.")
 }
 
 func (suite *TcpListenerTestSuite) Test_FailingCloseConnectionShouldSilentlyFail() {
@@ -429,6 +936,7 @@ func (suite *TcpListenerTestSuite) Test_FailingCloseConnectionShouldSilentlyFail
 	l := mocks.NewMockListener()
 	l.On("Accept").Return(c, nil)
 	l.On("Close").Return(nil)
+	l.On("Addr").Return(mockAddr)
 	nl := mocks.MockNetListener{}
 	nl.On("Listen").Return(l, nil)
 
@@ -438,7 +946,7 @@ func (suite *TcpListenerTestSuite) Test_FailingCloseConnectionShouldSilentlyFail
 	s.On("Err").Return(nil)
 	newScanner := mocks.NewMockNewScanner(&s)
 
-	listener, err := tcp_listener.New(8080, WAIT_PERIOD, &tcp_listener.TcpListenerDeps{Logger: logger, Listener: &nl, NewScanner: newScanner})
+	listener, err := tcp_listener.New("localhost:8080", WAIT_PERIOD, tcp_listener.Options{}, &tcp_listener.TcpListenerDeps{Logger: logger, Listener: &nl, NewScanner: newScanner})
 	go listener.Start()
 
 	time.Sleep(1 * time.Second)
@@ -447,9 +955,95 @@ func (suite *TcpListenerTestSuite) Test_FailingCloseConnectionShouldSilentlyFail
 
 	suite.NotNil(listener)
 	suite.Nil(err)
-	suite.Contains(logs.Last(), "Error closing connection.")
+	suite.Contains(logs.All(), "Error closing connection.")
 }
 
-func rndPort() uint16 {
-	return uint16(rand.Intn(65536-10000) + 10000)
+func (suite *TcpListenerTestSuite) Test_ScenarioFaults() {
+	tests := []struct {
+		name          string
+		fault         scenario.Fault
+		expectedLines []string
+		expectedTail  string
+		expectedErr   error
+	}{
+		{
+			name:        "Close without response",
+			fault:       scenario.FaultDrop,
+			expectedErr: io.EOF,
+		},
+		{
+			name:         "Half-written response",
+			fault:        scenario.FaultPartial,
+			expectedTail: "RESPONSE|ACCEPTED|T",
+			expectedErr:  io.EOF,
+		},
+		{
+			name:          "Malformed line",
+			fault:         scenario.FaultMalformed,
+			expectedLines: []string{"RESPONSE|ACCEPTED"},
+			expectedErr:   os.ErrDeadlineExceeded,
+		},
+		{
+			name:          "Duplicate response",
+			fault:         scenario.FaultDuplicate,
+			expectedLines: []string{"RESPONSE|ACCEPTED|Transaction processed", "RESPONSE|ACCEPTED|Transaction processed"},
+			expectedErr:   os.ErrDeadlineExceeded,
+		},
+		{
+			name:         "Response without trailing newline",
+			fault:        scenario.FaultNoNewline,
+			expectedTail: "RESPONSE|ACCEPTED|Transaction processed",
+			expectedErr:  os.ErrDeadlineExceeded,
+		},
+		{
+			name:        "Stall",
+			fault:       scenario.FaultStall,
+			expectedErr: os.ErrDeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			logger := zerolog.New(os.Stdout).With().Timestamp().Logger()
+			rules, err := scenario.Parse([]byte(fmt.Sprintf("faults: [{amounts: [13], fault: %s}]", tt.fault)))
+			suite.Require().NoError(err)
+
+			listenerMetrics := metrics.NewListener(metrics.NewRegistry())
+			listener, err := tcp_listener.New("localhost:0", 100*time.Millisecond, tcp_listener.Options{}, &tcp_listener.TcpListenerDeps{Logger: logger, Listener: tcp_listener.NetListener{}, NewScanner: tcp_listener.BufioScanner{}, Scenario: rules, Metrics: listenerMetrics})
+			suite.Require().NoError(err)
+			go listener.Start()
+			defer listener.Stop()
+
+			conn, err := net.Dial("tcp", listener.Addr().String())
+			suite.Require().NoError(err, "Failed to connect to server")
+			defer conn.Close()
+
+			_, err = fmt.Fprintf(conn, "PAYMENT|13\n")
+			suite.NoError(err, "Failed to send request")
+
+			err = conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+			suite.Require().NoError(err)
+
+			reader := bufio.NewReader(conn)
+			var lines []string
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					suite.ErrorIs(err, tt.expectedErr)
+					suite.Equal(tt.expectedTail, line, "Unexpected trailing data")
+					break
+				}
+				lines = append(lines, strings.TrimSpace(line))
+			}
+			suite.Equal(tt.expectedLines, lines, "Unexpected responses")
+
+			// stalled requests complete when the listener stops
+			listener.Stop()
+			address := listener.Addr().String()
+			suite.Eventually(func() bool {
+				return listenerMetrics.FaultedRequests.Value(address, string(tt.fault)) == 1
+			}, time.Second, 10*time.Millisecond, "Faulted request was not counted")
+			suite.Equal(0.0, listenerMetrics.Requests.Value(address, "ACCEPTED", "Transaction processed"))
+		})
+	}
 }
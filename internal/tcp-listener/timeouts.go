@@ -0,0 +1,90 @@
+package tcp_listener
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+var (
+	errIdleTimeout  = errors.New("idle timeout")
+	errReadTimeout  = errors.New("read timeout")
+	errWriteTimeout = errors.New("write timeout")
+)
+
+// Timeouts close the connections of slow or silent clients. Zero disables a timeout.
+// Deadlines are set on the sockets, so they always follow the real clock.
+type Timeouts struct {
+	// Idle is the time a connection can wait for the next request with none in flight,
+	// including the TLS handshake.
+	Idle time.Duration
+	// Read is the time a client can take to send a request line once it has started.
+	Read time.Duration
+	// Write is the time a client can take to receive a response.
+	Write time.Duration
+}
+
+// deadlineReader sets the read deadline of the connection before each read, to the idle
+// timeout between requests and to the read timeout within a request line.
+type deadlineReader struct {
+	connection *connection
+	timeouts   Timeouts
+	partial    bool
+	timedOut   bool
+}
+
+func (r *deadlineReader) Read(p []byte) (int, error) {
+	timeout, reason := r.timeouts.Read, errReadTimeout
+	if !r.partial {
+		timeout, reason = r.timeouts.Idle, errIdleTimeout
+		// connections with requests in flight are not idle
+		if r.connection.isActive() {
+			timeout = 0
+		}
+	}
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if err := r.connection.SetReadDeadline(deadline); err != nil {
+		return 0, err
+	}
+
+	n, err := r.connection.Read(p)
+	if n > 0 {
+		r.partial = p[n-1] != '\n'
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		r.timedOut = true
+		err = fmt.Errorf("%w: %w", reason, err)
+	}
+	return n, err
+}
+
+// idleDeadline starts the idle timeout of a pipelined connection once its last request in
+// flight completes.
+func (l *TcpListener) idleDeadline(connection *connection) {
+	if l.options.Timeouts.Idle <= 0 || connection.isActive() {
+		return
+	}
+	err := connection.SetReadDeadline(time.Now().Add(l.options.Timeouts.Idle))
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		l.deps.Logger.Error().Err(err).Msg("Error setting connection deadline.")
+	}
+}
+
+func (l *TcpListener) logClosing(connection *connection, reason error) {
+	l.deps.Logger.Info().Uint64("connection", connection.id).Str("reason", reason.Error()).Msg("Closing connection.")
+}
+
+// timeoutReason returns the read timeout that closed the connection, if any.
+func timeoutReason(err error) (error, bool) {
+	for _, reason := range []error{errIdleTimeout, errReadTimeout} {
+		if errors.Is(err, reason) {
+			return reason, true
+		}
+	}
+	return nil, false
+}
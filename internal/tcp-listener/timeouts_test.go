@@ -0,0 +1,114 @@
+package tcp_listener_test
+
+import (
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	"github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"github.com/rs/zerolog"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const TIMEOUT = 100 * time.Millisecond
+
+type TimeoutsTestSuite struct {
+	suite.Suite
+	logs *logSink
+}
+
+func TestTimeoutsSuite(t *testing.T) {
+	suite.Run(t, &TimeoutsTestSuite{})
+}
+
+func (suite *TimeoutsTestSuite) start(timeouts tcp_listener.Timeouts, rules *scenario.Scenario) *testConn {
+	suite.logs = &logSink{}
+	logger := zerolog.New(suite.logs).With().Timestamp().Logger()
+	listener := startListener(suite.T(), tcp_listener.Options{Timeouts: timeouts}, tcp_listener.TcpListenerDeps{Logger: logger, Scenario: rules})
+	return dial(suite.T(), listener.Addr().String())
+}
+
+// closedWithin waits for the connection to be closed by the server, and returns how long
+// it took.
+func (suite *TimeoutsTestSuite) closedWithin(conn *testConn) time.Duration {
+	start := time.Now()
+	_, err := conn.read()
+	suite.ErrorIs(err, io.EOF, "Connection should be closed")
+	return time.Since(start)
+}
+
+func (suite *TimeoutsTestSuite) Test_IdleConnectionIsClosed() {
+	conn := suite.start(tcp_listener.Timeouts{Idle: TIMEOUT}, nil)
+
+	duration := suite.closedWithin(conn)
+
+	suite.GreaterOrEqual(duration, TIMEOUT-10*time.Millisecond, "Connection was closed before the idle timeout")
+	suite.Eventually(func() bool {
+		return strings.Contains(suite.logs.All(), `"reason":"idle timeout"`)
+	}, time.Second, 10*time.Millisecond, "Timeout was not logged")
+}
+
+func (suite *TimeoutsTestSuite) Test_IdleTimeoutRestartsAfterEachResponse() {
+	conn := suite.start(tcp_listener.Timeouts{Idle: TIMEOUT}, nil)
+
+	for range 3 {
+		time.Sleep(TIMEOUT / 2)
+		suite.Equal("RESPONSE|ACCEPTED|Transaction processed", conn.send("PAYMENT|10"))
+	}
+
+	suite.closedWithin(conn)
+}
+
+func (suite *TimeoutsTestSuite) Test_RequestsInFlightAreNotIdle() {
+	conn := suite.start(tcp_listener.Timeouts{Idle: TIMEOUT}, nil)
+
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", conn.send("PAYMENT|300"))
+
+	suite.closedWithin(conn)
+}
+
+func (suite *TimeoutsTestSuite) Test_PipelinedRequestsInFlightAreNotIdle() {
+	conn := suite.start(tcp_listener.Timeouts{Idle: TIMEOUT}, nil)
+
+	conn.write("PIPELINE\na|PAYMENT|300")
+	for _, expected := range []string{"RESPONSE|ACCEPTED|Pipelining enabled", "a|RESPONSE|ACCEPTED|Transaction processed"} {
+		response, err := conn.read()
+		suite.NoError(err, "Failed to read response")
+		suite.Equal(expected, response)
+	}
+
+	duration := suite.closedWithin(conn)
+
+	suite.GreaterOrEqual(duration, TIMEOUT-10*time.Millisecond, "Idle timeout did not restart after the last response")
+}
+
+func (suite *TimeoutsTestSuite) Test_SlowRequestLineIsClosed() {
+	conn := suite.start(tcp_listener.Timeouts{Idle: time.Minute, Read: TIMEOUT}, nil)
+
+	_, err := fmt.Fprintf(conn, "PAYMENT|")
+	suite.NoError(err, "Failed to send request")
+
+	duration := suite.closedWithin(conn)
+
+	suite.Less(duration, time.Second, "Connection was not closed at the read timeout")
+	suite.Eventually(func() bool {
+		return strings.Contains(suite.logs.All(), `"reason":"read timeout"`)
+	}, time.Second, 10*time.Millisecond, "Timeout was not logged")
+}
+
+func (suite *TimeoutsTestSuite) Test_ClientNotReadingIsClosed() {
+	// a response larger than the socket buffers, so writing it blocks
+	rules := &scenario.Scenario{Rules: []scenario.Rule{{Status: scenario.StatusRejected, Reason: strings.Repeat("x", 16<<20)}}}
+	conn := suite.start(tcp_listener.Timeouts{Write: TIMEOUT}, rules)
+
+	conn.write("PAYMENT|10")
+
+	suite.Eventually(func() bool {
+		return strings.Contains(suite.logs.All(), `"reason":"write timeout"`)
+	}, time.Second, 10*time.Millisecond, "Timeout was not logged")
+	_, err := io.Copy(io.Discard, conn)
+	suite.NoError(err, "Connection should be closed")
+}
@@ -0,0 +1,78 @@
+package tcp_listener
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+)
+
+type TLSListener struct {
+	Config *tls.Config
+}
+
+func (s TLSListener) Listen(network string, address string) (net.Listener, error) {
+	return tls.Listen(network, address, s.Config)
+}
+
+// NewTLSConfig loads the server certificate and key. If clientCAFile is set, clients must
+// present a certificate signed by one of its CAs.
+func NewTLSConfig(certFile string, keyFile string, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if clientCAFile == "" {
+		return config, nil
+	}
+
+	bundle, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("no certificates found in %s", clientCAFile)
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	return config, nil
+}
+
+// handshake completes the TLS handshake of TLS connections, so clients with an invalid
+// certificate are rejected before reading any request, and stores the client
+// certificate subject.
+func (l *TcpListener) handshake(connection *connection) error {
+	tlsConn, ok := connection.Conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	ctx := l.ctx
+	if l.options.Timeouts.Idle > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.options.Timeouts.Idle)
+		defer cancel()
+	}
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		if !errors.Is(err, net.ErrClosed) {
+			l.deps.Logger.Error().Err(err).Uint64("connection", connection.id).Msg("TLS handshake failed.")
+		}
+		return err
+	}
+
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		l.deps.Logger.Info().Uint64("connection", connection.id).Msg("TLS connection established.")
+		return nil
+	}
+	subject := state.PeerCertificates[0].Subject.String()
+	l.mu.Lock()
+	connection.clientSubject = subject
+	l.mu.Unlock()
+	l.deps.Logger.Info().Uint64("connection", connection.id).Str("subject", subject).Msg("TLS connection established.")
+	return nil
+}
@@ -0,0 +1,108 @@
+package tcp_listener_test
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/internal/certs"
+	"github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"github.com/rs/zerolog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TLSTestSuite struct {
+	suite.Suite
+	address string
+	dir     string
+	logs    *logSink
+}
+
+func TestTLSSuite(t *testing.T) {
+	suite.Run(t, &TLSTestSuite{})
+}
+
+func (suite *TLSTestSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+	err := certs.Generate(suite.dir, certs.Options{Hosts: []string{"localhost", "127.0.0.1"}, ClientName: "participant-1"})
+	suite.Require().NoError(err)
+
+	config, err := tcp_listener.NewTLSConfig(
+		filepath.Join(suite.dir, certs.ServerFile),
+		filepath.Join(suite.dir, certs.ServerKeyFile),
+		filepath.Join(suite.dir, certs.CAFile),
+	)
+	suite.Require().NoError(err)
+
+	suite.logs = &logSink{}
+	logger := zerolog.New(suite.logs).With().Timestamp().Logger()
+	listener := startListener(suite.T(), tcp_listener.Options{}, tcp_listener.TcpListenerDeps{Logger: logger, Listener: tcp_listener.TLSListener{Config: config}})
+	suite.address = listener.Addr().String()
+}
+
+func (suite *TLSTestSuite) Test_ClientWithValidCertificate() {
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(suite.dir, certs.ClientFile), filepath.Join(suite.dir, certs.ClientKeyFile))
+	suite.Require().NoError(err)
+
+	response, err := suite.send(&tls.Config{Certificates: []tls.Certificate{clientCert}, RootCAs: suite.rootCAs()})
+
+	suite.NoError(err)
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", response)
+	suite.Contains(suite.logs.All(), "CN=participant-1")
+}
+
+func (suite *TLSTestSuite) Test_ClientWithoutCertificateIsRejected() {
+	_, err := suite.send(&tls.Config{RootCAs: suite.rootCAs()})
+
+	suite.Error(err)
+	suite.Contains(suite.logs.All(), "TLS handshake failed.")
+}
+
+func (suite *TLSTestSuite) Test_ClientWithUntrustedCertificateIsRejected() {
+	otherDir := suite.T().TempDir()
+	err := certs.Generate(otherDir, certs.Options{ClientName: "intruder"})
+	suite.Require().NoError(err)
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(otherDir, certs.ClientFile), filepath.Join(otherDir, certs.ClientKeyFile))
+	suite.Require().NoError(err)
+
+	_, err = suite.send(&tls.Config{Certificates: []tls.Certificate{clientCert}, RootCAs: suite.rootCAs()})
+
+	suite.Error(err)
+	suite.Contains(suite.logs.All(), "TLS handshake failed.")
+	suite.NotContains(suite.logs.All(), "CN=intruder")
+}
+
+func (suite *TLSTestSuite) Test_InvalidTLSConfiguration() {
+	_, err := tcp_listener.NewTLSConfig(filepath.Join(suite.dir, "missing.pem"), filepath.Join(suite.dir, certs.ServerKeyFile), "")
+	suite.Error(err)
+
+	_, err = tcp_listener.NewTLSConfig(filepath.Join(suite.dir, certs.ServerFile), filepath.Join(suite.dir, certs.ServerKeyFile), filepath.Join(suite.dir, certs.ServerKeyFile))
+	suite.ErrorContains(err, "no certificates found")
+}
+
+func (suite *TLSTestSuite) send(config *tls.Config) (string, error) {
+	conn, err := tls.Dial("tcp", suite.address, config)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintf(conn, "PAYMENT|10\n"); err != nil {
+		return "", err
+	}
+	response, err := bufio.NewReader(conn).ReadString('\n')
+	return strings.TrimSpace(response), err
+}
+
+func (suite *TLSTestSuite) rootCAs() *x509.CertPool {
+	ca, err := os.ReadFile(filepath.Join(suite.dir, certs.CAFile))
+	suite.Require().NoError(err)
+	pool := x509.NewCertPool()
+	suite.Require().True(pool.AppendCertsFromPEM(ca))
+	return pool
+}
@@ -0,0 +1,144 @@
+// Package simtest runs the scheme simulator inside Go tests, so payment clients can be
+// tested against it without starting a separate process:
+//
+//	sim := simtest.Start(t, simtest.WithScenario(`rules: [{status: REJECTED, reason: Closed}]`))
+//	conn, err := net.Dial("tcp", sim.Addr)
+//	...
+//	entries := sim.WaitForJournal(1)
+package simtest
+
+import (
+	"github.com/form3tech-oss/interview-simulator/internal/journal"
+	"github.com/form3tech-oss/interview-simulator/internal/scenario"
+	tcp_listener "github.com/form3tech-oss/interview-simulator/internal/tcp-listener"
+	"github.com/rs/zerolog"
+	"testing"
+	"time"
+)
+
+// JournalTimeout is how long WaitForJournal waits for the requests to be recorded.
+const JournalTimeout = 5 * time.Second
+
+// Entry is a request handled by the simulator, recorded once its response is sent.
+type Entry = journal.Entry
+
+type config struct {
+	address     string
+	gracePeriod time.Duration
+	scenario    func() (*scenario.Scenario, error)
+}
+
+type Option func(*config)
+
+// WithAddress binds the simulator to the address instead of a random port on localhost.
+func WithAddress(address string) Option {
+	return func(c *config) {
+		c.address = address
+	}
+}
+
+// WithGracePeriod gives the in-flight requests time to complete when the test finishes.
+// By default they are cancelled straight away.
+func WithGracePeriod(gracePeriod time.Duration) Option {
+	return func(c *config) {
+		c.gracePeriod = gracePeriod
+	}
+}
+
+// WithScenario sets the rules deciding the outcome of the payments, in the YAML or JSON
+// format of the scenario files.
+func WithScenario(rules string) Option {
+	return func(c *config) {
+		c.scenario = func() (*scenario.Scenario, error) {
+			return scenario.Parse([]byte(rules))
+		}
+	}
+}
+
+// WithScenarioFile loads the rules deciding the outcome of the payments from a scenario file.
+func WithScenarioFile(path string) Option {
+	return func(c *config) {
+		c.scenario = func() (*scenario.Scenario, error) {
+			return scenario.Load(path)
+		}
+	}
+}
+
+// Simulator is a simulator running for the duration of a test.
+type Simulator struct {
+	// Addr is the address the simulator is listening on, e.g. "127.0.0.1:41234".
+	Addr string
+
+	t        testing.TB
+	listener *tcp_listener.TcpListener
+	journal  *journal.Memory
+}
+
+// Start starts a simulator that is ready to accept connections once Start returns. It is
+// stopped when the test and its subtests complete.
+func Start(t testing.TB, opts ...Option) *Simulator {
+	t.Helper()
+	c := &config{address: "127.0.0.1:0"}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	var rules *scenario.Scenario
+	if c.scenario != nil {
+		var err error
+		rules, err = c.scenario()
+		if err != nil {
+			t.Fatalf("Error loading scenario: %v", err)
+		}
+	}
+
+	j := journal.NewMemory()
+	listener, err := tcp_listener.New(c.address, c.gracePeriod, tcp_listener.Options{}, &tcp_listener.TcpListenerDeps{
+		Logger:     zerolog.New(zerolog.NewTestWriter(t)).With().Timestamp().Logger(),
+		Listener:   tcp_listener.NetListener{},
+		NewScanner: tcp_listener.BufioScanner{},
+		Scenario:   rules,
+		Journal:    j,
+	})
+	if err != nil {
+		t.Fatalf("Error starting simulator: %v", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		listener.Start()
+	}()
+	t.Cleanup(func() {
+		listener.Stop()
+		<-stopped
+	})
+
+	return &Simulator{Addr: listener.Addr().String(), t: t, listener: listener, journal: j}
+}
+
+// Journal returns the requests handled so far, in the order their responses were sent.
+func (s *Simulator) Journal() []Entry {
+	return s.journal.Entries()
+}
+
+// WaitForJournal waits for at least n requests to be handled and returns them, failing
+// the test if they are not handled within JournalTimeout.
+func (s *Simulator) WaitForJournal(n int) []Entry {
+	s.t.Helper()
+	entries, ok := s.journal.Wait(n, JournalTimeout)
+	if !ok {
+		s.t.Fatalf("Expected %d requests in the journal, got %d", n, len(entries))
+	}
+	return entries
+}
+
+// SetScenario replaces the rules deciding the outcome of the payments received from now on.
+func (s *Simulator) SetScenario(rules string) {
+	s.t.Helper()
+	parsed, err := scenario.Parse([]byte(rules))
+	if err != nil {
+		s.t.Fatalf("Error parsing scenario: %v", err)
+	}
+	s.listener.SetScenario(parsed)
+}
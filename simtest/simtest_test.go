@@ -0,0 +1,87 @@
+package simtest_test
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/form3tech-oss/interview-simulator/simtest"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SimtestTestSuite struct {
+	suite.Suite
+}
+
+func TestSimtestSuite(t *testing.T) {
+	suite.Run(t, &SimtestTestSuite{})
+}
+
+func (suite *SimtestTestSuite) send(addr string, request string) string {
+	conn, err := net.Dial("tcp", addr)
+	suite.Require().NoError(err, "Failed to connect to simulator")
+	defer conn.Close()
+
+	_, err = fmt.Fprintf(conn, request+"\n")
+	suite.Require().NoError(err, "Failed to send request")
+	response, err := bufio.NewReader(conn).ReadString('\n')
+	suite.Require().NoError(err, "Failed to read response")
+	return strings.TrimSpace(response)
+}
+
+func (suite *SimtestTestSuite) Test_SimulatorIsReadyWhenStarted() {
+	sim := simtest.Start(suite.T())
+
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", suite.send(sim.Addr, "PAYMENT|10"))
+}
+
+func (suite *SimtestTestSuite) Test_SimulatorIsStoppedOnCleanup() {
+	var addr string
+	suite.Run("with simulator", func() {
+		addr = simtest.Start(suite.T()).Addr
+	})
+
+	_, err := net.Dial("tcp", addr)
+	suite.Error(err, "Simulator should be stopped")
+}
+
+func (suite *SimtestTestSuite) Test_JournalRecordsRequests() {
+	sim := simtest.Start(suite.T())
+
+	suite.send(sim.Addr, "PAYMENT|10")
+	suite.send(sim.Addr, "PAYMENT|abc")
+
+	entries := sim.WaitForJournal(2)
+
+	suite.Len(entries, 2)
+	suite.Equal("PAYMENT|10", entries[0].Request)
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", entries[0].Response)
+	suite.Equal("PAYMENT|abc", entries[1].Request)
+	suite.Equal("RESPONSE|REJECTED|Invalid amount", entries[1].Response)
+	suite.Equal(entries, sim.Journal())
+}
+
+func (suite *SimtestTestSuite) Test_Scenario() {
+	sim := simtest.Start(suite.T(), simtest.WithScenario(`rules: [{amount: {min: 1000}, status: REJECTED, reason: Limit exceeded}]`))
+
+	suite.Equal("RESPONSE|REJECTED|Limit exceeded", suite.send(sim.Addr, "PAYMENT|1000"))
+	suite.Equal("RESPONSE|ACCEPTED|Transaction processed", suite.send(sim.Addr, "PAYMENT|999"))
+
+	sim.SetScenario(`rules: [{status: REJECTED, reason: Scheme closed}]`)
+
+	suite.Equal("RESPONSE|REJECTED|Scheme closed", suite.send(sim.Addr, "PAYMENT|999"))
+}
+
+func (suite *SimtestTestSuite) Test_ScenarioFile() {
+	path := filepath.Join(suite.T().TempDir(), "scenario.yaml")
+	err := os.WriteFile(path, []byte(`rules: [{amounts: [42], status: REJECTED, reason: Unlucky}]`), 0o644)
+	suite.Require().NoError(err)
+
+	sim := simtest.Start(suite.T(), simtest.WithScenarioFile(path))
+
+	suite.Equal("RESPONSE|REJECTED|Unlucky", suite.send(sim.Addr, "PAYMENT|42"))
+}